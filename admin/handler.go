@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koykov/cbytecache"
+)
+
+const keysPrefix = "/keys/"
+
+var (
+	ErrForbidden  = errors.New("operation forbidden")
+	ErrKeyMissing = errors.New("key missing")
+)
+
+// Authorizer is the interface that wraps the basic Authorize method.
+//
+// Authorize checks if request allowed to perform mutating operation (delete, evict, vacuum, dump and reset) or to
+// read entry body. Non-nil error denies the request.
+type Authorizer interface {
+	Authorize(r *http.Request) error
+}
+
+// AuthorizerFunc is an adapter to use ordinary functions as Authorizer.
+type AuthorizerFunc func(r *http.Request) error
+
+func (f AuthorizerFunc) Authorize(r *http.Request) error {
+	return f(r)
+}
+
+// AllowAll is an Authorizer that allows all requests.
+//
+// Use it only if handler is protected outside (private network, auth middleware, ...).
+var AllowAll = AuthorizerFunc(func(_ *http.Request) error { return nil })
+
+// Handler is a http.Handler implementation that provides admin operations over the cache:
+// * GET /keys/{key} - inspect entry (size, expire timestamp and TTL), use ?body=true to get entry body
+// * DELETE /keys/{key} - delete entry
+// * POST /evict - force eviction of expired entries
+// * POST /vacuum - force vacuum
+// * POST /dump - force dump
+// * POST /reset - reset the cache
+//
+// All mutating operations and body reads go through Authorizer. If authorizer omit, they are forbidden.
+type Handler struct {
+	cache *cbytecache.Cache
+	auth  Authorizer
+	now   func() time.Time
+
+	pool sync.Pool
+}
+
+// NewHandler makes new Handler instance with given cache and authorizer.
+func NewHandler(cache *cbytecache.Cache, auth Authorizer) (*Handler, error) {
+	if cache == nil {
+		return nil, cbytecache.ErrBadCache
+	}
+	h := Handler{
+		cache: cache,
+		auth:  auth,
+		now:   time.Now,
+	}
+	return &h, nil
+}
+
+// WithClock sets the clock to calculate entries TTL.
+//
+// Use the same clock as in cache config.
+func (h *Handler) WithClock(clock cbytecache.Clock) *Handler {
+	if clock != nil {
+		h.now = clock.Now
+	}
+	return h
+}
+
+// Entry is a JSON representation of the entry.
+type Entry struct {
+	Key    string `json:"key"`
+	Size   int    `json:"size"`
+	Expire uint32 `json:"expire"`
+	TTL    string `json:"ttl"`
+	Body   []byte `json:"body,omitempty"`
+}
+
+// Result represents response of mutating operation.
+type Result struct {
+	Op    string `json:"op"`
+	Error string `json:"error,omitempty"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if strings.HasPrefix(path, keysPrefix) {
+		key := path[len(keysPrefix):]
+		if len(key) == 0 {
+			h.error(w, http.StatusBadRequest, ErrKeyMissing)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			h.get(w, r, key)
+		case http.MethodDelete:
+			h.exec(w, r, "delete", func() error { return h.cache.Delete(key) })
+		default:
+			h.notAllowed(w, "GET, HEAD, DELETE")
+		}
+		return
+	}
+
+	var fn func() error
+	switch path {
+	case "/evict":
+		fn = h.cache.Evict
+	case "/vacuum":
+		fn = h.cache.Vacuum
+	case "/dump":
+		fn = h.cache.Dump
+	case "/reset":
+		fn = h.cache.Reset
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.notAllowed(w, "POST")
+		return
+	}
+	h.exec(w, r, path[1:], fn)
+}
+
+// Inspect entry.
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, key string) {
+	// Entry body may contain sensitive data, so it requires authorization like mutating operations.
+	withBody := false
+	if v := r.URL.Query().Get("body"); v == "true" || v == "1" {
+		if !h.authorize(w, r) {
+			return
+		}
+		withBody = true
+	}
+
+	var buf []byte
+	if raw := h.pool.Get(); raw != nil {
+		buf = raw.([]byte)
+	}
+	defer func() { h.pool.Put(buf[:0]) }()
+
+	e, err := h.cache.GetEntryTo(buf[:0], key)
+	buf = e.Body
+	if err != nil {
+		h.error(w, status(err), err)
+		return
+	}
+	resp := Entry{
+		Key:    key,
+		Size:   len(e.Body),
+		Expire: e.Expire,
+		TTL:    time.Unix(int64(e.Expire), 0).Sub(h.now()).Truncate(time.Second).String(),
+	}
+	if withBody {
+		resp.Body = e.Body
+	}
+	h.json(w, http.StatusOK, resp)
+}
+
+// Authorize and execute mutating operation.
+func (h *Handler) exec(w http.ResponseWriter, r *http.Request, op string, fn func() error) {
+	if !h.authorize(w, r) {
+		return
+	}
+	if err := fn(); err != nil {
+		h.json(w, status(err), Result{Op: op, Error: err.Error()})
+		return
+	}
+	h.json(w, http.StatusOK, Result{Op: op})
+}
+
+// Check request authorization. Writes forbidden response and returns false if request denied.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if h.auth == nil {
+		h.error(w, http.StatusForbidden, ErrForbidden)
+		return false
+	}
+	if err := h.auth.Authorize(r); err != nil {
+		h.error(w, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) notAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	h.error(w, http.StatusMethodNotAllowed, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+}
+
+func (h *Handler) error(w http.ResponseWriter, code int, err error) {
+	h.json(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func (h *Handler) json(w http.ResponseWriter, code int, x any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(x)
+}
+
+// Get HTTP status code of cache error.
+func status(err error) int {
+	// Bulk operations wrap errors of buckets.
+	switch {
+	case errors.Is(err, cbytecache.ErrNotFound), errors.Is(err, cbytecache.ErrMissing):
+		return http.StatusNotFound
+	case errors.Is(err, cbytecache.ErrBucketService), errors.Is(err, cbytecache.ErrCacheClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, cbytecache.ErrNoDumpWriter):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+var _ = NewHandler
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestHandler(t *testing.T) {
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	if err = cache.Set("foo/bar", []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := AuthorizerFunc(func(r *http.Request) error {
+		if r.Header.Get("X-Token") != "secret" {
+			return errors.New("bad token")
+		}
+		return nil
+	})
+	h, err := NewHandler(cache, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	t.Run("get", func(t *testing.T) {
+		resp := do(t, http.MethodGet, srv.URL+"/keys/foo/bar?body=true", "secret")
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status mismatch: need %d got %d", http.StatusOK, resp.StatusCode)
+		}
+		var e Entry
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Size != 6 || string(e.Body) != "foobar" || e.Expire == 0 {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	})
+	t.Run("get body forbidden", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodGet, srv.URL+"/keys/foo/bar?body=true", ""), http.StatusForbidden)
+		assertStatus(t, do(t, http.MethodGet, srv.URL+"/keys/foo/bar", ""), http.StatusOK)
+	})
+	t.Run("get 404", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodGet, srv.URL+"/keys/qwe", ""), http.StatusNotFound)
+	})
+	t.Run("delete forbidden", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodDelete, srv.URL+"/keys/foo/bar", ""), http.StatusForbidden)
+	})
+	t.Run("delete", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodDelete, srv.URL+"/keys/foo/bar", "secret"), http.StatusOK)
+		if _, err := cache.Get("foo/bar"); err != cbytecache.ErrNotFound {
+			t.Errorf("entry must be deleted, got error %v", err)
+		}
+	})
+	t.Run("evict", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodPost, srv.URL+"/evict", "secret"), http.StatusOK)
+	})
+	t.Run("dump", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodPost, srv.URL+"/dump", "secret"), http.StatusNotImplemented)
+	})
+	t.Run("method", func(t *testing.T) {
+		assertStatus(t, do(t, http.MethodGet, srv.URL+"/reset", "secret"), http.StatusMethodNotAllowed)
+	})
+	t.Run("no auth", func(t *testing.T) {
+		h1, _ := NewHandler(cache, nil)
+		srv1 := httptest.NewServer(h1)
+		defer srv1.Close()
+		assertStatus(t, do(t, http.MethodPost, srv1.URL+"/reset", "secret"), http.StatusForbidden)
+	})
+}
+
+func do(t *testing.T, method, url, token string) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) > 0 {
+		req.Header.Set("X-Token", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func assertStatus(t *testing.T, resp *http.Response, code int) {
+	_ = resp.Body.Close()
+	if resp.StatusCode != code {
+		t.Errorf("status mismatch: need %d got %d", code, resp.StatusCode)
+	}
+}
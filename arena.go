@@ -34,13 +34,13 @@ func (a *arena) full() bool {
 	return !a.released() && a.h.Len == a.h.Cap
 }
 
-// Write b to arena.
+// Reserve n bytes of arena free space to write them later.
 //
 // Caution! No bounds check control. External code must guarantee bounds safety.
-func (a *arena) write(b []byte) (n int) {
-	n = cbyte.Memcpy(uint64(a.h.Data), uint64(a.h.Len), b)
-	a.h.Len += n
-	return
+func (a *arena) reserve(n uint32) []byte {
+	p := a.read(a.offset(), n)
+	a.h.Len += int(n)
+	return p
 }
 
 // Read bytes from arena using offset and length.
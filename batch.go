@@ -0,0 +1,105 @@
+package cbytecache
+
+// Batch is a reusable buffer of batch operations (GetMany, SetMany, DeleteMany).
+//
+// Batch keeps per-key results in input order. Reuse the same batch to avoid allocations. Batch isn't thread-safe.
+type Batch struct {
+	// Per-key data.
+	item []batchItem
+	// Positions of items grouped by buckets.
+	order []int
+	// Counters of items per bucket.
+	cnt []int
+	// Entries bodies storage.
+	buf []byte
+}
+
+// Internal batch item.
+type batchItem struct {
+	hash   uint64
+	bucket uint
+	tenant uint16
+	// Body position in batch buffer.
+	lo, hi int
+	err    error
+}
+
+// Len returns count of batch items.
+func (b *Batch) Len() int {
+	return len(b.item)
+}
+
+// Get returns entry body and error of i-th key.
+//
+// Body stays valid until batch reuse.
+func (b *Batch) Get(i int) ([]byte, error) {
+	if i < 0 || i >= len(b.item) {
+		return nil, ErrNotFound
+	}
+	itm := &b.item[i]
+	if itm.err != nil {
+		return nil, itm.err
+	}
+	return b.buf[itm.lo:itm.hi], nil
+}
+
+// Err returns error of i-th key.
+func (b *Batch) Err(i int) error {
+	if i < 0 || i >= len(b.item) {
+		return ErrNotFound
+	}
+	return b.item[i].err
+}
+
+// Reset clears batch data keeping allocated buffers for further use.
+func (b *Batch) Reset() {
+	b.item = b.item[:0]
+	b.order = b.order[:0]
+	b.buf = b.buf[:0]
+}
+
+// Register item with hash h and bucket index.
+func (b *Batch) add(h uint64, bucket uint, err error) {
+	b.item = append(b.item, batchItem{hash: h, bucket: bucket, err: err})
+}
+
+// Group items positions by buckets using counting sort. Items with errors skip.
+//
+// Returns slice of bucket bounds in order slice: items of bucket i are order[bounds[i]:bounds[i+1]].
+func (b *Batch) group(buckets uint) []int {
+	n := int(buckets) + 1
+	if cap(b.cnt) < n {
+		b.cnt = make([]int, n)
+	}
+	b.cnt = b.cnt[:n]
+	for i := range b.cnt {
+		b.cnt[i] = 0
+	}
+	var c int
+	for i := range b.item {
+		if b.item[i].err == nil {
+			b.cnt[b.item[i].bucket+1]++
+			c++
+		}
+	}
+	for i := 1; i < n; i++ {
+		b.cnt[i] += b.cnt[i-1]
+	}
+	if cap(b.order) < c {
+		b.order = make([]int, c)
+	}
+	b.order = b.order[:c]
+	// Use order positions as write cursors, bounds restores after placement.
+	for i := range b.item {
+		if itm := &b.item[i]; itm.err == nil {
+			b.order[b.cnt[itm.bucket]] = i
+			b.cnt[itm.bucket]++
+		}
+	}
+	// Shift cursors back to get bounds.
+	for i := n - 1; i > 0; i-- {
+		b.cnt[i] = b.cnt[i-1]
+	}
+	b.cnt[0] = 0
+	return b.cnt
+}
@@ -2,6 +2,7 @@ package cbytecache
 
 import (
 	"encoding/binary"
+	"runtime"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -21,17 +22,40 @@ type bucket struct {
 	size   bucketSize
 
 	mux sync.RWMutex
+	// Count of optimistic readers and writers that copy entries data outside the lock.
+	readers, writers int32
+	// Arenas epoch. Increases on every service operation, so optimistic readers and writers may check arenas memory
+	// they copied weren't modified in the meantime.
+	epoch uint32
 	// Internal buffer.
 	buf *cbytebuf.CByteBuf
+	// Events buffer.
+	ebuf []byte
 	// Entry index. Value point to the index in entry array.
 	index map[uint64]uint32
 	// Entries storage.
 	entry []entry
+	// Known missing entries. Value is expire timestamp.
+	miss map[uint64]uint32
+	// Namespaces and tenants accounting.
+	nsq, tnq quotaSet
+	// Tags index (tag hash to entries hashes) and entries tags (entry hash to tags hashes).
+	tags, etags map[uint64][]uint64
+	// Last assigned entry version.
+	ver uint64
 
 	// Memory arenas.
 	queue arenaQueue
+	// Refresh pool (optional).
+	rp *refreshPool
+	// Asynchronous expire listener (optional).
+	al *asyncListener
+	// Optional metrics writers.
+	lmw ListenerMetricsWriter
+	mmw MissingMetricsWriter
 
 	lastEvc, lastVac time.Time
+	durEvc, durVac   time.Duration
 }
 
 // Make and init new bucket.
@@ -43,25 +67,38 @@ func newBucket(id uint32, config *Config, maxCap uint64) *bucket {
 		maxCap: uint32(maxCap),
 		buf:    cbytebuf.NewCByteBuf(),
 		index:  make(map[uint64]uint32),
+		miss:   make(map[uint64]uint32),
+		lmw:    listenerMetricsOf(config.MetricsWriter),
+		mmw:    missingMetricsOf(config.MetricsWriter),
+		// Versions start from current time, so entries loaded after restart don't repeat versions issued before.
+		ver: uint64(time.Now().UnixNano()),
 	}
+	b.nsq.init()
+	b.tnq.init()
 	b.queue.setHead(nil).setAct(nil).setTail(nil)
 	return &b
 }
 
-// Set p to bucket by h hash.
-func (b *bucket) set(key string, h uint64, p []byte) (err error) {
+// Set p with tags hashes to bucket by h hash in namespace ns on behalf of tenant tn.
+func (b *bucket) set(key string, h uint64, p []byte, ns, tn uint16, tags []uint64) (err error) {
+	if b.config.OptimisticReads {
+		return b.setOpt(key, h, p, ns, tn, tags)
+	}
 	if err = b.checkStatus(); err != nil {
 		return
 	}
 
 	b.mux.Lock()
-	err = b.setLF(key, h, p, 0)
+	if err = b.setLF(key, h, p, 0, ns, tn); err == nil {
+		b.tagLF(h, tags)
+		b.mutateLastLF()
+	}
 	b.mux.Unlock()
 	return
 }
 
-// Set m to bucket by h hash.
-func (b *bucket) setm(key string, h uint64, m MarshallerTo) (err error) {
+// Set m to bucket by h hash on behalf of tenant tn.
+func (b *bucket) setm(key string, h uint64, m MarshallerTo, tn uint16) (err error) {
 	if err = b.checkStatus(); err != nil {
 		return
 	}
@@ -73,12 +110,17 @@ func (b *bucket) setm(key string, h uint64, m MarshallerTo) (err error) {
 	if _, err = b.buf.WriteMarshallerTo(m); err != nil {
 		return
 	}
-	err = b.setLF(key, h, b.buf.Bytes(), 0)
+	if err = b.setLF(key, h, b.buf.Bytes(), 0, 0, tn); err == nil {
+		b.mutateLastLF()
+	}
 	return
 }
 
 // Internal setter. It works in lock-free mode thus need to guarantee thread-safety outside.
-func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32) (err error) {
+//
+// Param ns is a namespace ID of the entry, zero means default namespace. Param tn is a tenant ID of the entry, zero
+// means no tenant.
+func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32, ns, tn uint16) (err error) {
 	var (
 		idx, pl uint32
 
@@ -93,10 +135,14 @@ func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32) (err error
 		if idx < b.elen() {
 			e = &b.entry[idx]
 		}
+		// Expired entry (possibly kept due to grace period) doesn't prevent write. It deletes after successful write.
+		if e != nil && e.expire < b.now() {
+			e = nil
+		}
 	}
 
 	// Extend entry data with collision control data.
-	if p, pl, err = b.c7n(key, p); err != nil {
+	if p, pl, err = b.c7n(key, p, ns); err != nil {
 		return
 	}
 
@@ -115,10 +161,11 @@ func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32) (err error
 			if key1, dst, err = b.getLF(dst[:0], e, dummyMetrics); err != nil {
 				return
 			}
-			if key1 != key {
+			if key1 != key || e.ns != ns {
 				// Keys don't match - collision caught.
-				if b.l() != nil {
-					b.l().Printf("collision %d: keys \"%s\" and \"%s\"", h, key, key1)
+				if logEnabled(b.l(), LevelWarn) {
+					b.l().Log(LevelWarn, "keys collision",
+						Field{"bucket", b.idx}, Field{"hash", h}, Field{"key", key}, Field{"key1", key1})
 				}
 				b.mw().Collision(b.ids)
 				err = ErrEntryCollision
@@ -130,58 +177,45 @@ func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32) (err error
 		return
 	}
 
-	// Init alloc.
-	if b.queue.len() == 0 {
-		a := b.queue.alloc(nil, b.acap())
-		b.queue.setHead(a).setAct(a)
-		b.mw().Alloc(b.ids, b.acap())
-		b.size.snap(snapAlloc, b.acap())
-	}
-	// Get current arena.
-	a := b.queue.act()
-	startArena := a
-	arenaOffset, arenaRest := a.offset(), a.rest()
-	rest := uint32(len(p))
-	if arenaRest >= rest {
-		// Arena has enough space to write the entry.
-		a.write(p)
-	} else {
-		// Arena hasn't enough space - need share entry among arenas.
-		// New arena allocation will need, so check if it's possible.
-		_, full, _ := b.queue.stat()
-		if b.maxCap > 0 && full*b.acap()+b.acap() > b.maxCap {
-			// Allocation denied, thus stop write at all.
-			b.mw().NoSpace(b.ids)
-			return ErrNoSpace
-		}
-		// Share entry among current and next arenas.
-		mustWrite := arenaRest
-		for {
-			// Write entry bytes that fits to arena free space.
-			a.write(p[:mustWrite])
-			p = p[mustWrite:]
-			if rest -= mustWrite; rest == 0 {
-				// All entry bytes written.
-				break
-			}
-			// Switch to the next arena.
-			prev := a
-			a = a.next()
-			b.queue.setAct(a)
-			b.mw().Fill(b.ids, b.acap())
-			// Alloc new arena if needed.
-			if a == nil {
-				a = b.queue.alloc(prev, b.acap())
-				b.mw().Alloc(b.ids, b.acap())
-				prev.setNext(a)
-				b.queue.setAct(a).setTail(a)
-				b.size.snap(snapAlloc, b.acap())
-			}
-			// Calculate rest of bytes to write.
-			mustWrite = umin32(rest, b.acap())
-		}
+	return b.putLF(h, p, pl, expire, ns, tn, EventExpire, stm)
+}
+
+// Internal setter that overwrites existing entry. It works in lock-free mode thus need to guarantee thread-safety
+// outside.
+//
+// Existing entry keeps until the new one is written, so failed write (no space, quota exceeded, ...) doesn't lose it.
+func (b *bucket) overwriteLF(key string, h uint64, p []byte, expire uint32, ns, tn uint16) (err error) {
+	var (
+		pl  uint32
+		stm = b.nowT()
+	)
+	defer b.buf.ResetLen()
+	if p, pl, err = b.c7n(key, p, ns); err != nil {
+		return
+	}
+	return b.putLF(h, p, pl, expire, ns, tn, eventNone, stm)
+}
+
+// Write entry data p extended with collision control data and register the entry instead of existing one.
+//
+// Existing entry deletes with event of type typ only after successful write.
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) putLF(h uint64, p []byte, pl, expire uint32, ns, tn uint16, typ EventType, stm time.Time) (err error) {
+	// Check namespace and tenant quotas. Existing entry releases its quota on overwrite.
+	if !b.allowOverLF(ns, tn, pl, b.entryLF(h)) {
+		return ErrQuotaExceeded
 	}
 
+	// Reserve arenas space and write entry data.
+	var buf [optimisticSpans][]byte
+	spans, startArena, arenaOffset, err := b.reserveReclaimLF(buf[:0], h, tn, pl)
+	if err != nil {
+		return
+	}
+	w := spanWriter{spans: spans}
+	w.write(p)
+	_ = b.delLF(h, typ)
+
 	// Create and register new entry.
 	e1 := entry{
 		hash:   h,
@@ -190,12 +224,17 @@ func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32) (err error
 		expire: expire,
 		aid:    startArena.id,
 		qp:     b.queue.ptr(),
+		ns:     ns,
+		tn:     tn,
+		ver:    b.nextVersionLF(),
 	}
 	if e1.expire == 0 {
 		e1.expire = uint32(b.config.Clock.Now().Add(b.config.ExpireInterval).Unix())
 	}
 	b.entry = append(b.entry, e1)
 	b.index[h] = b.elen() - 1
+	b.delMissingLF(h)
+	b.accountLF(&b.entry[b.elen()-1])
 
 	b.size.snap(snapSet, pl)
 	b.mw().Set(b.ids, b.nowT().Sub(stm))
@@ -204,47 +243,120 @@ func (b *bucket) setLF(key string, h uint64, p []byte, expire uint32) (err error
 
 // Get entry by h hash.
 func (b *bucket) get(dst []byte, h uint64, del bool) ([]byte, error) {
+	e, err := b.getEntry(dst, h, del, false)
+	return e.Body, err
+}
+
+// Get entry (key, body and expire timestamp) by h hash.
+//
+// Entry key and body point to dst and stay valid until dst reuse. If stale flag enabled, expired entry within grace
+// period returns together with ErrStale.
+func (b *bucket) getEntry(dst []byte, h uint64, del, stale bool) (Entry, error) {
+	if !del && b.config.OptimisticReads {
+		return b.getEntryOpt(dst, h, stale)
+	}
 	if err := b.checkStatus(); err != nil {
-		return dst, err
+		return Entry{Body: dst}, err
 	}
 
 	if del {
 		b.mux.Lock()
 		defer b.mux.Unlock()
+		return b.getEntryLF(dst, h, true, stale)
+	}
+	return b.getEntryLocked(dst, h, stale)
+}
+
+// Get entry by h hash under read lock.
+func (b *bucket) getEntryLocked(dst []byte, h uint64, stale bool) (Entry, error) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.getEntryLF(dst, h, false, stale)
+}
+
+// Get entry by h hash in lock-free mode.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) getEntryLF(dst []byte, h uint64, del, stale bool) (Entry, error) {
+	stm := b.nowT()
+	e, err := b.lookupLF(h, stale)
+	if err != nil && err != ErrStale {
+		return Entry{Body: dst}, err
+	}
+
+	r := Entry{Expire: e.expire, Namespace: e.ns, Version: e.ver}
+	var err1 error
+	if r.Key, r.Body, err1 = b.getLF(dst, e, b.mw()); err1 != nil {
+		err = err1
 	} else {
-		b.mux.RLock()
-		defer b.mux.RUnlock()
+		b.mw().Hit(b.ids, b.nowT().Sub(stm))
 	}
-	var (
-		idx uint32
-		ok  bool
-		stm = b.nowT()
-	)
-	if idx, ok = b.index[h]; !ok {
-		b.mw().Miss(b.ids)
-		return dst, ErrNotFound
+
+	if del {
+		err = b.delLF(h, EventExtract)
 	}
-	if idx >= b.elen() {
+
+	return r, err
+}
+
+// Get indexed entry by h hash, possibly expired. It works in lock-free mode thus need to guarantee thread-safety
+// outside.
+func (b *bucket) entryLF(h uint64) *entry {
+	if idx, ok := b.index[h]; ok && idx < b.elen() {
+		return &b.entry[idx]
+	}
+	return nil
+}
+
+// Lookup alive entry by h hash. It works in lock-free mode thus need to guarantee thread-safety outside.
+//
+// If stale flag enabled, expired entry within grace period returns together with ErrStale.
+func (b *bucket) lookupLF(h uint64, stale bool) (*entry, error) {
+	idx, ok := b.index[h]
+	if !ok || idx >= b.elen() {
+		if b.missingLF(h) {
+			b.mmw.HitMissing(b.ids)
+			return nil, ErrMissing
+		}
 		b.mw().Miss(b.ids)
-		return dst, ErrNotFound
+		return nil, ErrNotFound
 	}
 	e := &b.entry[idx]
-	if e.expire < b.now() {
+	if now := b.now(); e.expire < now {
 		b.mw().Expire(b.ids)
-		return dst, ErrNotFound
+		if stale && e.expire+b.grace() >= now {
+			return e, ErrStale
+		}
+		return nil, ErrNotFound
 	}
+	return e, ErrOK
+}
 
-	var err error
-	_, dst, err = b.getLF(dst, e, b.mw())
-	if err == nil {
-		b.mw().Hit(b.ids, b.nowT().Sub(stm))
+// Replace entry by h hash with p.
+//
+// Replace allows only if entry (possibly expired) still exists and has ver version, otherwise ErrEntryVersion returns.
+// Thus background replace (refresh, revalidation) doesn't restore deleted entry or overwrite the newer one.
+// Replaced entry keeps its namespace, tenant and tags.
+func (b *bucket) replace(key string, h uint64, p []byte, ver uint64) (err error) {
+	if err = b.checkStatus(); err != nil {
+		return
 	}
 
-	if del {
-		err = b.delLF(h)
+	b.mux.Lock()
+	e := b.entryLF(h)
+	if e == nil || e.ver != ver {
+		b.mux.Unlock()
+		return ErrEntryVersion
 	}
-
-	return dst, err
+	ns, tn := e.ns, e.tn
+	// Delete doesn't modify tags slice, just removes it from index.
+	tags := b.tagsLF(h)
+	if err = b.overwriteLF(key, h, p, 0, ns, tn); err == nil {
+		b.tagLF(h, tags)
+		b.mutateLastLF()
+	}
+	b.mux.Unlock()
+	return
 }
 
 // Internal getter. It works in lock-free mode thus need to guarantee thread-safety outside.
@@ -280,21 +392,37 @@ func (b *bucket) getLF(dst []byte, entry *entry, mw MetricsWriter) (string, []by
 		}
 	}
 
-	if len(dst) < keySizeBytes {
+	return unpack(dst, entry.ns)
+}
+
+// Split entry data to key and body using key length stored in the last bytes.
+//
+// Entries of non-default namespace ns contain namespace ID right before key length, it checks for data consistency.
+func unpack(dst []byte, ns uint16) (string, []byte, error) {
+	tl := keySizeBytes
+	if ns != 0 {
+		tl += nsSizeBytes
+	}
+	if len(dst) < tl {
 		return "", dst, ErrEntryCorrupt
 	}
 	l := len(dst)
 	kl := binary.LittleEndian.Uint16(dst[l-keySizeBytes:])
-	if l-2 <= int(kl) {
+	if l-tl <= int(kl) {
+		return "", dst, ErrEntryCorrupt
+	}
+	if ns != 0 && binary.LittleEndian.Uint16(dst[l-tl:]) != ns {
 		return "", dst, ErrEntryCorrupt
 	}
-	key := byteconv.B2S(dst[l-int(kl)-keySizeBytes : l-keySizeBytes])
+	key := byteconv.B2S(dst[l-int(kl)-tl : l-tl])
 
-	return key, dst[:l-int(kl)-keySizeBytes], ErrOK
+	return key, dst[:l-int(kl)-tl], ErrOK
 }
 
 // Extend entry with collision control data.
-func (b *bucket) c7n(key string, p []byte) ([]byte, uint32, error) {
+//
+// Entries of non-default namespace ns also contain namespace ID between key and key length.
+func (b *bucket) c7n(key string, p []byte, ns uint16) ([]byte, uint32, error) {
 	pl := uint32(len(p))
 	var err error
 	// Write payload at start.
@@ -305,8 +433,16 @@ func (b *bucket) c7n(key string, p []byte) ([]byte, uint32, error) {
 	if _, err = b.buf.WriteString(key); err != nil {
 		return p, pl, err
 	}
-	// Write key length to the last two bytes.
+	// Write namespace ID.
 	bl := b.buf.Len()
+	if ns != 0 {
+		if err = b.buf.GrowLen(bl + nsSizeBytes); err != nil {
+			return p, pl, err
+		}
+		binary.LittleEndian.PutUint16(b.buf.Bytes()[bl:], ns)
+		bl += nsSizeBytes
+	}
+	// Write key length to the last two bytes.
 	if err = b.buf.GrowLen(bl + keySizeBytes); err != nil {
 		return p, pl, err
 	}
@@ -329,11 +465,14 @@ func (b *bucket) del(h uint64) error {
 	b.mux.Lock()
 	defer b.mux.Unlock()
 
-	return b.delLF(h)
+	b.delMissingLF(h)
+	return b.delLF(h, EventDelete)
 }
 
 // Delete entry in lock-free mode.
-func (b *bucket) delLF(h uint64) error {
+//
+// Event of type typ sends to event listener before delete.
+func (b *bucket) delLF(h uint64, typ EventType) error {
 	idx, ok := b.index[h]
 	if !ok {
 		return nil
@@ -341,6 +480,9 @@ func (b *bucket) delLF(h uint64) error {
 	if idx >= b.elen() {
 		return nil
 	}
+	b.event(&b.entry[idx], typ)
+	b.unaccountLF(&b.entry[idx])
+	b.untagLF(h)
 	b.entry[idx].destroy()
 	delete(b.index, h)
 	b.mw().Del(b.ids)
@@ -359,8 +501,10 @@ func (b *bucket) reset() error {
 	defer b.svcUnlock()
 
 	b.buf.ResetLen()
+	b.eventRange(len(b.entry), EventReset)
 	b.evictRange(len(b.entry))
 	b.entry = b.entry[:0]
+	b.resetMissingLF()
 
 	return ErrOK
 }
@@ -374,15 +518,20 @@ func (b *bucket) release() error {
 	}
 
 	var c int
-	b.svcLock()
+	b.svcLockFree()
+	stm := b.nowT()
 	defer func() {
-		if b.l() != nil {
-			b.l().Printf("bucket #%d: release %d arenas", b.idx, c)
+		if logEnabled(b.l(), LevelDebug) {
+			b.l().Log(LevelDebug, "release arenas",
+				Field{"bucket", b.idx}, Field{"op", "release"}, Field{"arenas", c}, Field{"duration", b.nowT().Sub(stm)})
 		}
 		b.svcUnlock()
 	}()
 
 	b.buf.Release()
+	// Send events before arenas release.
+	b.eventRange(len(b.entry), EventRelease)
+	b.resetMissingLF()
 
 	var wg sync.WaitGroup
 
@@ -420,9 +569,36 @@ func (b *bucket) release() error {
 }
 
 // Lock bucket for service operation.
+//
+// Service operations may reset arenas, so wait for optimistic writers that copy entries data to reserved arenas space
+// outside the lock and increase arenas epoch. Optimistic readers don't wait since they check epoch after copy.
+// Optimistic readers and writers register under the lock, so no one new may appear after lock.
 func (b *bucket) svcLock() {
 	atomic.StoreUint32(&b.status, bucketStatusService)
 	b.mux.Lock()
+	// Concurrent service operation might reset status on unlock.
+	atomic.StoreUint32(&b.status, bucketStatusService)
+	b.quiesceLF()
+}
+
+// Wait for optimistic writers and invalidate optimistic reads before arenas modification.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) quiesceLF() {
+	for atomic.LoadInt32(&b.writers) > 0 {
+		runtime.Gosched()
+	}
+	atomic.AddUint32(&b.epoch, 1)
+}
+
+// Lock bucket for service operation that releases arenas memory.
+//
+// In addition to svcLock() waits for optimistic readers since released memory becomes unavailable to read.
+func (b *bucket) svcLockFree() {
+	b.svcLock()
+	for atomic.LoadInt32(&b.readers) > 0 {
+		runtime.Gosched()
+	}
 }
 
 // Unlock bucket after service operation.
@@ -471,12 +647,17 @@ func (b *bucket) mw() MetricsWriter {
 	return b.config.MetricsWriter
 }
 
+// Return grace period of expired entries in seconds.
+func (b *bucket) grace() uint32 {
+	return uint32(b.config.StaleInterval / time.Second)
+}
+
 // Shorthand arena capacity method.
 func (b *bucket) acap() uint32 {
 	return uint32(b.config.ArenaCapacity)
 }
 
 // Shorthand logger method.
-func (b *bucket) l() Logger {
-	return b.config.Logger
+func (b *bucket) l() StructLogger {
+	return b.config.StructLogger
 }
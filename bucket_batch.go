@@ -0,0 +1,71 @@
+package cbytecache
+
+// Get batch items on positions pos under single lock.
+func (b *bucket) getMany(batch *Batch, pos []int) {
+	if err := b.checkStatus(); err != nil {
+		batchFail(batch, pos, err)
+		return
+	}
+
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	for _, i := range pos {
+		itm := &batch.item[i]
+		stm := b.nowT()
+		e, err := b.lookupLF(itm.hash, false)
+		if err != nil {
+			itm.err = err
+			continue
+		}
+		lo := len(batch.buf)
+		var body []byte
+		if _, body, err = b.getLF(batch.buf, e, b.mw()); err != nil {
+			batch.buf = batch.buf[:lo]
+			itm.err = err
+			continue
+		}
+		// Cut off collision control data.
+		batch.buf = body
+		itm.lo, itm.hi = lo, len(body)
+		b.mw().Hit(b.ids, b.nowT().Sub(stm))
+	}
+}
+
+// Set batch entries on positions pos under single lock.
+func (b *bucket) setMany(batch *Batch, entries []Entry, pos []int) {
+	if err := b.checkStatus(); err != nil {
+		batchFail(batch, pos, err)
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for _, i := range pos {
+		itm := &batch.item[i]
+		if itm.err = b.setLF(entries[i].Key, itm.hash, entries[i].Body, 0, 0, itm.tenant); itm.err == nil {
+			b.mutateLastLF()
+		}
+	}
+}
+
+// Delete batch items on positions pos under single lock.
+func (b *bucket) delMany(batch *Batch, pos []int) {
+	if err := b.checkStatus(); err != nil {
+		batchFail(batch, pos, err)
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for _, i := range pos {
+		itm := &batch.item[i]
+		itm.err = b.delLF(itm.hash, EventDelete)
+	}
+}
+
+// Mark batch items on positions pos as failed.
+func batchFail(batch *Batch, pos []int, err error) {
+	for _, i := range pos {
+		batch.item[i].err = err
+	}
+}
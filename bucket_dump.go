@@ -1,20 +1,20 @@
 package cbytecache
 
-import (
-	"sort"
-)
-
-// Perform bulk dumping operation.
-func (b *bucket) bulkDump() error {
-	if err := b.checkStatus(); err != nil {
+// Perform bulk dumping operation to writer w.
+//
+// Param wait allows to wait for concurrent service operation instead of skip the bucket.
+func (b *bucket) bulkDump(w DumpWriter, wait bool) error {
+	if err := b.checkStatus(); err != nil && (!wait || err != ErrBucketService) {
 		return err
 	}
 
 	var c int
 	b.svcLock()
+	stm := b.nowT()
 	defer func() {
-		if b.l() != nil {
-			b.l().Printf("bucket #%d: dump %d entries", b.idx, c)
+		if logEnabled(b.l(), LevelDebug) {
+			b.l().Log(LevelDebug, "dump entries",
+				Field{"bucket", b.idx}, Field{"op", "dump"}, Field{"count", c}, Field{"duration", b.nowT().Sub(stm)})
 		}
 		b.buf.ResetLen()
 		b.svcUnlock()
@@ -28,13 +28,12 @@ func (b *bucket) bulkDump() error {
 	buf := b.entry
 	now := b.now()
 	_ = buf[el-1]
-	z := sort.Search(int(el), func(i int) bool {
-		return now <= buf[i].expire
-	})
-
-	_ = buf[el-1]
-	for i := z; i < int(el); i++ {
-		b.dump(&buf[i])
+	for i := 0; i < int(el); i++ {
+		// Expire order of entries isn't guaranteed (see Cache.SetEntry), so check every entry.
+		if buf[i].invalid() || buf[i].expire < now {
+			continue
+		}
+		b.dump(&buf[i], w)
 		c++
 	}
 
@@ -42,7 +41,7 @@ func (b *bucket) bulkDump() error {
 }
 
 // Perform dump operation over single entry.
-func (b *bucket) dump(e *entry) {
+func (b *bucket) dump(e *entry, w DumpWriter) {
 	if e.invalid() {
 		return
 	}
@@ -52,6 +51,6 @@ func (b *bucket) dump(e *entry) {
 	if err != nil {
 		return
 	}
-	_, _ = b.config.DumpWriter.Write(Entry{Key: key, Body: body, Expire: e.expire})
+	_, _ = w.Write(Entry{Key: key, Body: body, Expire: e.expire, Namespace: e.ns, Tags: b.copyTagsLF(e.hash)})
 	b.mw().Dump(b.ids)
 }
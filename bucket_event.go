@@ -0,0 +1,61 @@
+package cbytecache
+
+// Send event of type typ for all alive entries on range [0..z).
+//
+// This method has sense only if event listener is provided in config.
+func (b *bucket) eventRange(z int, typ EventType) {
+	if b.config.EventListener == nil || z == 0 {
+		return
+	}
+	_ = b.entry[z-1]
+	for i := 0; i < z; i++ {
+		b.event(&b.entry[i], typ)
+	}
+}
+
+// Send event of type typ for single entry to event listener and mutation stream.
+func (b *bucket) event(e *entry, typ EventType) {
+	if typ == eventNone || e.invalid() {
+		return
+	}
+	el, ms := b.config.EventListener, b.config.MutationStream
+	mt, mok := mutationOf(typ)
+	if typ == eventSet {
+		// Internal type, only for mutation stream.
+		el = nil
+	}
+	if el == nil && (ms == nil || !mok) {
+		return
+	}
+	b.ebuf = b.ebuf[:0]
+	key, body, err := b.getLF(b.ebuf, e, dummyMetrics)
+	b.ebuf = body
+	if err != nil {
+		return
+	}
+	e1 := Entry{Key: key, Body: body, Expire: e.expire, Namespace: e.ns, Tags: b.copyTagsLF(e.hash)}
+	if el != nil {
+		if err = el.OnEvent(Event{Type: typ, Entry: e1}); err != nil {
+			b.lmw.ListenerError(b.ids)
+		}
+	}
+	if ms != nil && mok {
+		if err = ms.Push(Mutation{Type: mt, Entry: e1}); err != nil {
+			b.lmw.ListenerError(b.ids)
+		}
+	}
+}
+
+// Get mutation type corresponding to event type.
+func mutationOf(typ EventType) (MutationType, bool) {
+	switch typ {
+	case eventSet:
+		return MutationSet, true
+	case EventDelete, EventExtract, EventEvict:
+		return MutationDelete, true
+	case EventExpire:
+		return MutationExpire, true
+	default:
+		return 0, false
+	}
+}
@@ -1,26 +1,38 @@
 package cbytecache
 
 import (
-	"sort"
 	"sync"
 )
 
 // Perform bulk eviction operation.
-func (b *bucket) bulkEvict() (err error) {
+//
+// Param force disables skipping of eviction that happens too early after previous one.
+func (b *bucket) bulkEvict(force bool) (err error) {
 	if err = b.checkStatus(); err != nil {
 		return
 	}
 
-	var ac, ec int
+	var ac, ec, rc, dc, xc int
 	b.svcLock()
+	stm := b.nowT()
 	defer func() {
-		if b.l() != nil {
-			b.l().Printf("bucket #%d: evict %d entries and free up %d arenas", b.idx, ec, ac)
+		b.durEvc = b.nowT().Sub(stm)
+		if logEnabled(b.l(), LevelDebug) {
+			b.l().Log(LevelDebug, "evict entries",
+				Field{"bucket", b.idx}, Field{"op", "evict"}, Field{"count", ec}, Field{"arenas", ac},
+				Field{"refresh", rc}, Field{"refresh_drop", dc}, Field{"reclaim", xc}, Field{"duration", b.durEvc})
 		}
 		b.svcUnlock()
 	}()
 
-	ac, ec, err = b.bulkEvictLF(false)
+	b.evictMissingLF()
+	if ac, ec, err = b.bulkEvictLF(force); err != nil {
+		return
+	}
+	// Delete entries of namespaces and tenants over quota.
+	xc = b.reclaimLF()
+	// Enqueue entries near expiration for refresh.
+	rc, dc = b.refreshLF()
 	return
 }
 
@@ -40,24 +52,74 @@ func (b *bucket) bulkEvictLF(force bool) (ac, ec int, err error) {
 		return
 	}
 
-	buf := b.entry
-	now := b.now()
-	_ = buf[el-1]
-	z := sort.Search(int(el), func(i int) bool {
-		return now <= buf[i].expire
-	})
+	// Keep expired entries within grace period.
+	now := b.now() - b.grace()
+	// Entries with custom expire timestamp (see Cache.SetEntry) break expire order of entries, thus walk from the head
+	// while entries are expired or deleted. Alive entry that expires later than any entry with default expiration
+	// moves to the tail, otherwise it would keep head arenas from recycling.
+	lim := b.now() + uint32(b.config.ExpireInterval.Seconds())
+	var z int
+	for z < int(el) {
+		e := &b.entry[z]
+		if e.invalid() || e.expire < now || (e.expire > lim && b.relocateLF(z)) {
+			z++
+			continue
+		}
+		break
+	}
 	if z == 0 {
 		return
 	}
 	ec = z
+	ac = b.evictHeadLF(z)
+	return
+}
 
+// Move alive entry at position i to the tail of the queue. Returns false if bucket has no space to move the entry.
+//
+// Entry keeps its hash, version, tags and accounting. Its previous position remains invalid till eviction.
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) relocateLF(i int) bool {
+	defer b.buf.ResetLen()
+	e := b.entry[i]
+	b.buf.ResetLen()
+	if err := b.buf.GrowLen(int(e.length)); err != nil {
+		return false
+	}
+	// Read raw entry data, including collision control data.
+	p := b.buf.Bytes()[:0]
+	if _, _, err := b.getLF(p, &e, dummyMetrics); err != nil {
+		return false
+	}
+	p = p[:e.length]
+
+	var buf [optimisticSpans][]byte
+	spans, a, offset, err := b.reserveLF(buf[:0], e.length)
+	if err != nil {
+		return false
+	}
+	w := spanWriter{spans: spans}
+	w.write(p)
+
+	b.entry[i].destroy()
+	e.offset, e.aid = offset, a.id
+	b.entry = append(b.entry, e)
+	b.index[e.hash] = b.elen() - 1
+	b.size.snap(snapSet, e.length)
+	return true
+}
+
+// Evict entries on range [0..z) and recycle arenas contain only them. Returns count of reset arenas.
+//
+// All entries of the range must be expired or deleted.
+func (b *bucket) evictHeadLF(z int) (ac int) {
 	// Last arena contains unexpired entries.
-	lo := buf[z-1].arena()
+	lo := b.entry[z-1].arena()
 	// Previous arena must contain only expired entries.
 	lo1 := lo.prev()
 
-	if b.config.ExpireListener != nil {
-		// Call expire listener for all expired entries.
+	if b.config.ExpireListener != nil || b.config.EventListener != nil || b.config.MutationStream != nil {
+		// Call expire listeners for all expired entries.
 		b.expireRange(z)
 	}
 
@@ -96,6 +158,9 @@ func (b *bucket) bulkEvictLF(force bool) (ac, ec int, err error) {
 // Evict all entries on range [0..z).
 func (b *bucket) evictRange(z int) {
 	el := b.elen()
+	if el == 0 {
+		return
+	}
 	if z < 256 {
 		_ = b.entry[el-1]
 		for i := 0; i < z; i++ {
@@ -157,5 +222,7 @@ func (b *bucket) evict(e *entry) {
 	if e.invalid() {
 		return
 	}
+	b.unaccountLF(e)
+	b.untagLF(e.hash)
 	delete(b.index, e.hash)
 }
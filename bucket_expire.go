@@ -2,7 +2,7 @@ package cbytecache
 
 // Mark all entries on range [0..z) as expired.
 //
-// This method has sense only if expire listener is provided in config.
+// This method has sense only if expire listener, event listener or mutation stream is provided in config.
 func (b *bucket) expireRange(z int) {
 	el := b.elen()
 	if z < 256 {
@@ -34,6 +34,10 @@ func (b *bucket) expire(e *entry) {
 	if e.invalid() {
 		return
 	}
+	b.event(e, EventExpire)
+	if b.config.ExpireListener == nil {
+		return
+	}
 	// Get entry data (key, body and expire timestamp).
 	b.buf.ResetLen()
 	_ = b.buf.GrowLen(int(e.length))
@@ -42,5 +46,12 @@ func (b *bucket) expire(e *entry) {
 		return
 	}
 	// Pack entry and send it to the listener.
-	_ = b.config.ExpireListener.Listen(Entry{Key: key, Body: body})
+	e1 := Entry{Key: key, Body: body, Expire: e.expire, Namespace: e.ns, Tags: b.copyTagsLF(e.hash)}
+	if b.al != nil {
+		b.al.send(b.ids, e1)
+		return
+	}
+	if err = b.config.ExpireListener.Listen(e1); err != nil {
+		b.lmw.ListenerError(b.ids)
+	}
 }
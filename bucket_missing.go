@@ -0,0 +1,109 @@
+package cbytecache
+
+// Mark entry by h hash as known missing until expire timestamp.
+//
+// Existing entry deletes since it isn't actual anymore.
+func (b *bucket) setMissing(h uint64, expire uint32) error {
+	if err := b.checkStatus(); err != nil {
+		return err
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	_ = b.delLF(h, EventDelete)
+	if _, ok := b.miss[h]; !ok {
+		if uint(len(b.miss)) >= b.config.MissingLimit {
+			b.freeMissingLF()
+		}
+		b.size.snapMissing(1)
+	}
+	b.miss[h] = expire
+	b.mmw.SetMissing(b.ids)
+	return ErrOK
+}
+
+// Free space for the batch of missing marks.
+//
+// Expired marks remove first, then marks closest to expiration among few random marks. Full scan of expired marks
+// amortizes over the next marks of the batch.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) freeMissingLF() {
+	batch := int(b.config.MissingLimit / missingEvictBatch)
+	if batch == 0 {
+		batch = 1
+	}
+	for c := b.evictMissingLF(); c < batch && len(b.miss) > 0; c++ {
+		b.evictMissingSampleLF()
+	}
+}
+
+// Remove missing mark closest to expiration among few random marks.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) evictMissingSampleLF() {
+	var (
+		victim uint64
+		min    uint32
+		n      int
+	)
+	// Map iteration order is random.
+	for h, expire := range b.miss {
+		if n == 0 || expire < min {
+			victim, min = h, expire
+		}
+		if n++; n == missingEvictSamples {
+			break
+		}
+	}
+	if n > 0 {
+		b.delMissingLF(victim)
+	}
+}
+
+// Check if entry by h hash is known missing.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) missingLF(h uint64) bool {
+	expire, ok := b.miss[h]
+	return ok && expire >= b.now()
+}
+
+// Remove missing mark of the entry by h hash.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) delMissingLF(h uint64) {
+	if _, ok := b.miss[h]; ok {
+		delete(b.miss, h)
+		b.size.snapMissing(-1)
+	}
+}
+
+// Remove all expired missing marks.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) evictMissingLF() (c int) {
+	now := b.now()
+	for h, expire := range b.miss {
+		if expire < now {
+			delete(b.miss, h)
+			c++
+		}
+	}
+	if c > 0 {
+		b.size.snapMissing(-c)
+	}
+	return
+}
+
+// Remove all missing marks.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) resetMissingLF() {
+	if l := len(b.miss); l > 0 {
+		for h := range b.miss {
+			delete(b.miss, h)
+		}
+		b.size.snapMissing(-l)
+	}
+}
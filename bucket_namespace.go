@@ -0,0 +1,42 @@
+package cbytecache
+
+// Fold namespace ID ns into key hash h.
+//
+// Default namespace keeps hash as is, thus the same key in different namespaces gets different hashes and buckets.
+func nsHash(h uint64, ns uint16) uint64 {
+	if ns == 0 {
+		return h
+	}
+	// Spread namespace ID over all hash bits using 64-bit golden ratio.
+	return h ^ (uint64(ns) * 0x9e3779b97f4a7c15)
+}
+
+// Get size of alive entries of namespace ns.
+func (b *bucket) nsSize(ns uint16) uint32 {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.nsq.used[ns]
+}
+
+// Delete all entries of namespace ns.
+//
+// Entries data will keep in the arenas till the next eviction, but namespace quota releases immediately.
+func (b *bucket) resetNS(ns uint16) error {
+	if err := b.checkStatus(); err != nil {
+		return err
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if _, ok := b.nsq.used[ns]; !ok {
+		// Namespace has no alive entries in the bucket.
+		return ErrOK
+	}
+	for i := 0; i < len(b.entry); i++ {
+		if e := &b.entry[i]; e.ns == ns && !e.invalid() {
+			_ = b.delLF(e.hash, EventReset)
+		}
+	}
+	return ErrOK
+}
@@ -0,0 +1,212 @@
+package cbytecache
+
+// Accounting of alive entries bytes by owner ID (namespace or tenant) with optional per-bucket quotas.
+//
+// Zero owner ID means no owner and isn't accounted. It isn't thread-safe, bucket lock protects it.
+type quotaSet struct {
+	used, limit map[uint16]uint32
+}
+
+// Init internal maps.
+func (q *quotaSet) init() {
+	q.used = make(map[uint16]uint32)
+	q.limit = make(map[uint16]uint32)
+}
+
+// Set quota l of owner id. Zero quota means no limit.
+func (q *quotaSet) setLimit(id uint16, l uint32) {
+	if l == 0 {
+		delete(q.limit, id)
+		return
+	}
+	q.limit[id] = l
+}
+
+// Check if entry of size pl fits owner id quota.
+func (q *quotaSet) allow(id uint16, pl uint32) bool {
+	if id == 0 {
+		return true
+	}
+	l, ok := q.limit[id]
+	if !ok {
+		return true
+	}
+	return uint64(q.used[id])+uint64(pl) <= uint64(l)
+}
+
+// Check if owner id exceeds its quota.
+func (q *quotaSet) over(id uint16) bool {
+	if id == 0 {
+		return false
+	}
+	l, ok := q.limit[id]
+	return ok && q.used[id] > l
+}
+
+// Check if any owner exceeds its quota.
+func (q *quotaSet) overAny() bool {
+	for id := range q.limit {
+		if q.over(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Account entry of size pl by owner id.
+func (q *quotaSet) add(id uint16, pl uint32) {
+	if id == 0 {
+		return
+	}
+	q.used[id] += pl
+}
+
+// Remove entry of size pl from owner id accounting.
+func (q *quotaSet) drop(id uint16, pl uint32) {
+	if id == 0 {
+		return
+	}
+	if u := q.used[id]; u > pl {
+		q.used[id] = u - pl
+		return
+	}
+	delete(q.used, id)
+}
+
+// Set bucket quota q of namespace ns. Zero quota means no limit.
+func (b *bucket) setNSQuota(ns uint16, q uint32) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.nsq.setLimit(ns, q)
+}
+
+// Set bucket quota q of tenant tn. Zero quota means no limit.
+func (b *bucket) setTenantQuota(tn uint16, q uint32) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.tnq.setLimit(tn, q)
+}
+
+// Check if entry of size pl fits namespace ns and tenant tn quotas.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) allowLF(ns, tn uint16, pl uint32) bool {
+	return b.nsq.allow(ns, pl) && b.tnq.allow(tn, pl)
+}
+
+// Check if entry of size pl fits namespace ns and tenant tn quotas instead of existing entry prev.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) allowOverLF(ns, tn uint16, pl uint32, prev *entry) bool {
+	if prev == nil || prev.invalid() {
+		return b.allowLF(ns, tn, pl)
+	}
+	b.unaccountLF(prev)
+	ok := b.allowLF(ns, tn, pl)
+	b.accountLF(prev)
+	return ok
+}
+
+// Account new entry e.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) accountLF(e *entry) {
+	b.nsq.add(e.ns, e.length)
+	b.tnq.add(e.tn, e.length)
+}
+
+// Remove alive entry e from namespace and tenant accounting.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) unaccountLF(e *entry) {
+	if e.invalid() {
+		return
+	}
+	b.nsq.drop(e.ns, e.length)
+	b.tnq.drop(e.tn, e.length)
+}
+
+// Get size of alive entries of tenant tn.
+func (b *bucket) tenantSize(tn uint16) uint32 {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.tnq.used[tn]
+}
+
+// Delete oldest entries of namespaces and tenants that exceed their quotas, until they fit quotas again. Returns
+// count of deleted entries.
+//
+// Quota may be exceeded after its decrease or after assigning quota to already filled tenant. Deleted entries release
+// quota immediately, their arenas memory reclaims during further evictions.
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) reclaimLF() (c int) {
+	if !b.nsq.overAny() && !b.tnq.overAny() {
+		return
+	}
+	// Entries are ordered by write time, so the oldest entries go first.
+	for i := 0; i < len(b.entry); i++ {
+		e := &b.entry[i]
+		if e.invalid() || (!b.nsq.over(e.ns) && !b.tnq.over(e.tn)) {
+			continue
+		}
+		_ = b.delLF(e.hash, EventEvict)
+		c++
+	}
+	return
+}
+
+// Release arenas space of length l for entry by h hash of tenant tn deleting the oldest entries of the tenant that
+// exceeds its fair share of bucket capacity the most. Fair share is bucket capacity split among tenants that own
+// entries and tenant tn (entries without tenant count as one more tenant). Returns true if any arenas released.
+//
+// Only entries at the head of the queue may release arenas memory immediately, thus reclaim stops on the first alive
+// entry of other tenant (or on entry by h hash that overwrites). Expired entries of the head evict as well.
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) reclaimSpaceLF(h uint64, tn uint16, l uint32) bool {
+	if len(b.tnq.used) == 0 || b.maxCap == 0 {
+		return false
+	}
+	var (
+		victim uint16
+		over   uint32
+	)
+	n := len(b.tnq.used)
+	if _, ok := b.tnq.used[tn]; !ok {
+		n++
+	}
+	fair := b.maxCap / uint32(n)
+	for tn, u := range b.tnq.used {
+		if u > fair && u-fair > over {
+			victim, over = tn, u-fair
+		}
+	}
+	if victim == 0 {
+		return false
+	}
+
+	var (
+		z     int
+		freed uint32
+		now   = b.now()
+	)
+	// Release one extra arena since the arena of the last deleted entry may keep other entries.
+	for ; z < len(b.entry) && freed < l+b.acap(); z++ {
+		e := &b.entry[z]
+		if !e.invalid() && e.expire >= now && (e.tn != victim || e.hash == h) {
+			break
+		}
+		freed += e.length
+	}
+	if z == 0 || b.entry[z-1].arena().prev() == nil {
+		// Nothing to recycle, so keep victim entries.
+		return false
+	}
+	for i := 0; i < z; i++ {
+		if e := &b.entry[i]; !e.invalid() && e.expire >= now {
+			_ = b.delLF(e.hash, EventEvict)
+		}
+	}
+	b.quiesceLF()
+	b.evictHeadLF(z)
+	return true
+}
@@ -0,0 +1,111 @@
+package cbytecache
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Max number of arenas entry may share among to read it in optimistic mode.
+const optimisticSpans = 8
+
+var errSpanOverflow = errors.New("entry spans too many arenas")
+
+// Get entry by h hash in optimistic mode.
+//
+// Bucket lock holds only to lookup the entry and to resolve arenas memory spans contains entry data. Data copies to
+// dst after unlock, so concurrent writers don't wait for readers copying. Works like a seqlock: reader remembers arenas
+// epoch under the lock and checks it after copy. If service operation (evict, reset, ...) happens in the meantime,
+// copied data may be overwritten and entry reads again under the lock. Service operations that release arenas memory
+// (vacuum, release) wait for in-flight readers, see svcLockFree().
+func (b *bucket) getEntryOpt(dst []byte, h uint64, stale bool) (Entry, error) {
+	if err := b.checkStatus(); err != nil {
+		return Entry{Body: dst}, err
+	}
+
+	var (
+		buf   [optimisticSpans][]byte
+		spans [][]byte
+		r     Entry
+		stm   = b.nowT()
+		dl    = len(dst)
+	)
+
+	b.mux.RLock()
+	e, lerr := b.lookupLF(h, stale)
+	if lerr != nil && lerr != ErrStale {
+		b.mux.RUnlock()
+		return Entry{Body: dst}, lerr
+	}
+	r.Expire, r.Namespace, r.Version = e.expire, e.ns, e.ver
+	var err error
+	if spans, err = b.spanLF(buf[:0], e); err == errSpanOverflow {
+		// Entry is too long to collect its spans, so read it under the lock.
+		r.Key, r.Body, err = b.getLF(dst, e, b.mw())
+		b.mux.RUnlock()
+		if err != nil {
+			return r, err
+		}
+		b.mw().Hit(b.ids, b.nowT().Sub(stm))
+		return r, lerr
+	}
+	if err != nil {
+		b.mux.RUnlock()
+		return Entry{Body: dst}, err
+	}
+	// Register reader under the lock, so service operation can't miss it.
+	epoch := atomic.LoadUint32(&b.epoch)
+	atomic.AddInt32(&b.readers, 1)
+	b.mux.RUnlock()
+
+	for i := 0; i < len(spans); i++ {
+		dst = append(dst, spans[i]...)
+	}
+	valid := atomic.LoadUint32(&b.epoch) == epoch
+	atomic.AddInt32(&b.readers, -1)
+	if !valid {
+		// Arenas were modified during copy.
+		return b.getEntryLocked(dst[:dl], h, stale)
+	}
+	if r.Key, r.Body, err = unpack(dst, r.Namespace); err != nil {
+		return r, err
+	}
+	b.mw().Hit(b.ids, b.nowT().Sub(stm))
+	return r, lerr
+}
+
+// Collect arenas memory spans contains entry data to dst.
+//
+// Returns errSpanOverflow if entry shares among more arenas than dst capacity allows.
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) spanLF(dst [][]byte, entry *entry) ([][]byte, error) {
+	arenaOffset := entry.offset
+
+	a := entry.arena()
+	if a == nil {
+		b.mw().Miss(b.ids)
+		return dst, ErrNotFound
+	}
+
+	if entry.offset+entry.length < b.acap() {
+		return append(dst, a.read(arenaOffset, entry.length)), ErrOK
+	}
+	arenaRest := b.acap() - arenaOffset
+	rest := entry.length
+	for rest > 0 {
+		if len(dst) == cap(dst) {
+			return dst, errSpanOverflow
+		}
+		dst = append(dst, a.read(arenaOffset, arenaRest))
+		if rest -= arenaRest; rest == 0 {
+			break
+		}
+		a = a.next()
+		if a == nil {
+			b.mw().Corrupt(b.ids)
+			return dst, ErrEntryCorrupt
+		}
+		arenaOffset = 0
+		arenaRest = umin32(rest, b.acap())
+	}
+	return dst, ErrOK
+}
@@ -0,0 +1,233 @@
+package cbytecache
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/byteconv"
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestOptimisticRead(t *testing.T) {
+	const entries = 1e4
+
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 4
+	conf.ArenaCapacity = Kilobyte
+	conf.Clock = clock.NewClock()
+	conf.OptimisticReads = true
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done uint32
+	)
+	// Writers.
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var key []byte
+			for i := w; atomic.LoadUint32(&done) == 0; i += 4 {
+				key = makeKey(key, i%entries)
+				_ = cache.Set(byteconv.B2S(key), getEntryBody(i%entries))
+			}
+		}(w)
+	}
+	// Readers.
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			var key, dst []byte
+			for i := r; atomic.LoadUint32(&done) == 0; i++ {
+				key = makeKey(key, i%entries)
+				var err error
+				if dst, err = cache.GetTo(dst[:0], byteconv.B2S(key)); err == nil {
+					assertBytes(t, getEntryBody(i%entries), dst)
+				}
+			}
+		}(r)
+	}
+	// Trigger evictions while readers copy data.
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Millisecond * 20)
+		conf.Clock.Jump(time.Minute + time.Second)
+	}
+	atomic.StoreUint32(&done, 1)
+	wg.Wait()
+	_ = cache.Close()
+}
+
+func TestOptimisticService(t *testing.T) {
+	const entries = 1e3
+
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 2
+	conf.ArenaCapacity = Kilobyte
+	conf.OptimisticReads = true
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	var (
+		wg   sync.WaitGroup
+		done uint32
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var key, dst []byte
+			for i := w; atomic.LoadUint32(&done) == 0; i++ {
+				key = makeKey(key, i%entries)
+				if w%2 == 0 {
+					_ = cache.Set(byteconv.B2S(key), getEntryBody(i%entries))
+					continue
+				}
+				var err error
+				if dst, err = cache.GetTo(dst[:0], byteconv.B2S(key)); err == nil {
+					assertBytes(t, getEntryBody(i%entries), dst)
+				}
+			}
+		}(w)
+	}
+	// Concurrent service operations.
+	for s := 0; s < 2; s++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadUint32(&done) == 0 {
+				_ = cache.Reset()
+				_ = cache.Vacuum()
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 100)
+	atomic.StoreUint32(&done, 1)
+	wg.Wait()
+}
+
+func TestOptimisticWriteRollback(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 1
+	conf.ArenaCapacity = Kilobyte
+	conf.Clock = clock.NewClock()
+	conf.OptimisticReads = true
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	for i := 0; i < 4; i++ {
+		_ = cache.Set(strconv.Itoa(i), getEntryBody(i))
+	}
+	conf.Clock.Jump(time.Minute + time.Second)
+	_ = cache.Set("new", getEntryBody(4))
+
+	// Reserve entry like optimistic writer does.
+	b := cache.buckets[0]
+	b.mux.Lock()
+	_, a, offset, _ := b.reserveLF(nil, 64)
+	idx, rv := b.elen(), b.nextVersionLF()
+	expire := uint32(conf.Clock.Now().Add(conf.ExpireInterval).Unix())
+	b.entry = append(b.entry, entry{offset: offset, length: 64, expire: expire, aid: a.id, qp: b.queue.ptr(), ver: rv})
+	b.size.snap(snapSet, 64)
+	b.mux.Unlock()
+
+	// Eviction moves reserved entry towards the head.
+	_ = cache.Evict()
+	b.mux.Lock()
+	b.dropReservedLF(idx, rv)
+	var used uint32
+	for i := range b.entry {
+		if !b.entry[i].invalid() {
+			used += b.entry[i].length
+		}
+	}
+	b.mux.Unlock()
+	if _, u, _ := b.size.snapshot(); u != used {
+		t.Errorf("used size mismatch: need %d got %d", used, u)
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	const entries = 1e5
+	bench := func(b *testing.B, optimistic, write bool) {
+		conf := DefaultConfig(time.Hour, &fnv.Hasher{}, 0)
+		conf.OptimisticReads = optimistic
+		cache, err := New(conf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var key []byte
+		for i := 0; i < entries; i++ {
+			key = makeKey(key, i)
+			_ = cache.Set(byteconv.B2S(key), getEntryBody(i))
+		}
+		var ctr uint64
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			var key, dst []byte
+			wid := atomic.AddUint64(&ctr, 1)
+			i := int(wid) * 7919
+			for pb.Next() {
+				i++
+				if write && wid == 1 {
+					// One of the workers writes new entries.
+					key = append(key[:0], "new"...)
+					key = strconv.AppendInt(key, int64(i), 10)
+					_ = cache.Set(byteconv.B2S(key), getEntryBody(i))
+					continue
+				}
+				key = makeKey(key, i%entries)
+				dst, _ = cache.GetTo(dst[:0], byteconv.B2S(key))
+			}
+		})
+		b.StopTimer()
+		_ = cache.Close()
+	}
+	b.Run("locked", func(b *testing.B) { bench(b, false, false) })
+	b.Run("optimistic", func(b *testing.B) { bench(b, true, false) })
+	b.Run("locked/write", func(b *testing.B) { bench(b, false, true) })
+	b.Run("optimistic/write", func(b *testing.B) { bench(b, true, true) })
+}
+
+func BenchmarkWrite(b *testing.B) {
+	bench := func(b *testing.B, optimistic bool) {
+		conf := DefaultConfig(time.Hour, &fnv.Hasher{}, 0)
+		conf.OptimisticReads = optimistic
+		cache, err := New(conf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var ctr uint64
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			var key []byte
+			wid := atomic.AddUint64(&ctr, 1)
+			i := int(wid) << 32
+			for pb.Next() {
+				i++
+				key = strconv.AppendInt(key[:0], int64(i), 10)
+				_ = cache.Set(byteconv.B2S(key), getEntryBody(i))
+			}
+		})
+		b.StopTimer()
+		_ = cache.Close()
+	}
+	b.Run("locked", func(b *testing.B) { bench(b, false) })
+	b.Run("optimistic", func(b *testing.B) { bench(b, true) })
+}
@@ -0,0 +1,30 @@
+package cbytecache
+
+// Call fn for keys of alive entries.
+//
+// Unlike dump, it doesn't need service lock and waits for maintenance of the bucket under read lock.
+func (b *bucket) scanKeys(fn func(key string)) error {
+	if err := b.checkStatus(); err != nil && err != ErrBucketService {
+		return err
+	}
+
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	var buf []byte
+	now := b.now()
+	for i := 0; i < len(b.entry); i++ {
+		e := &b.entry[i]
+		if e.invalid() || e.expire < now {
+			continue
+		}
+		key, body, err := b.getLF(buf[:0], e, dummyMetrics)
+		// Body points to the start of buf, so keep it to reuse.
+		buf = body[:0]
+		if err != nil {
+			continue
+		}
+		fn(key)
+	}
+	return ErrOK
+}
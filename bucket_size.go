@@ -18,6 +18,8 @@ const (
 // Collection of bucket sizes.
 type bucketSize struct {
 	total, used, free uint32
+	// Count of known missing entries.
+	missing uint32
 }
 
 // Collect size for given snapshot type.
@@ -38,6 +40,16 @@ func (s *bucketSize) snap(op snap, size uint32) {
 	}
 }
 
+// Collect count of known missing entries.
+func (s *bucketSize) snapMissing(delta int) {
+	atomic.AddUint32(&s.missing, uint32(int32(delta)))
+}
+
+// Get count of known missing entries.
+func (s *bucketSize) missingSnapshot() uint32 {
+	return atomic.LoadUint32(&s.missing)
+}
+
 // Get collected size snapshot data.
 func (s *bucketSize) snapshot() (uint32, uint32, uint32) {
 	return atomic.LoadUint32(&s.total), atomic.LoadUint32(&s.used), atomic.LoadUint32(&s.free)
@@ -0,0 +1,131 @@
+package cbytecache
+
+// Register tags hashes of entry with h hash.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) tagLF(h uint64, tags []uint64) {
+	if len(tags) == 0 {
+		return
+	}
+	if b.tags == nil {
+		b.tags = make(map[uint64][]uint64)
+		b.etags = make(map[uint64][]uint64)
+	}
+	t := make([]uint64, 0, len(tags))
+	for _, th := range tags {
+		if hasTag(t, th) {
+			continue
+		}
+		t = append(t, th)
+		b.tags[th] = append(b.tags[th], h)
+	}
+	b.etags[h] = t
+}
+
+// Remove entry with h hash from tags index.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) untagLF(h uint64) {
+	if len(b.etags) == 0 {
+		return
+	}
+	t, ok := b.etags[h]
+	if !ok {
+		return
+	}
+	for _, th := range t {
+		hs := b.tags[th]
+		for i := 0; i < len(hs); i++ {
+			if hs[i] == h {
+				hs[i] = hs[len(hs)-1]
+				hs = hs[:len(hs)-1]
+				break
+			}
+		}
+		if len(hs) == 0 {
+			delete(b.tags, th)
+			continue
+		}
+		b.tags[th] = hs
+	}
+	delete(b.etags, h)
+}
+
+// Get tags hashes of entry with h hash.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) tagsLF(h uint64) []uint64 {
+	if len(b.etags) == 0 {
+		return nil
+	}
+	return b.etags[h]
+}
+
+// Get copy of tags hashes of entry with h hash. Use it for tags that leave the bucket (listeners, dumps, ...), so
+// receivers can't modify tags index.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) copyTagsLF(h uint64) []uint64 {
+	t := b.tagsLF(h)
+	if len(t) == 0 {
+		return nil
+	}
+	return append([]uint64(nil), t...)
+}
+
+// Delete all entries tagged with th tag hash. Returns count of deleted alive entries.
+func (b *bucket) delByTag(th uint64) (c int, err error) {
+	if err = b.checkStatus(); err != nil {
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	hs := b.tags[th]
+	if len(hs) == 0 {
+		return
+	}
+	// Delete modifies tags index, so iterate over a copy.
+	hs = append([]uint64(nil), hs...)
+	now := b.now()
+	for _, h := range hs {
+		idx, ok := b.index[h]
+		if !ok {
+			continue
+		}
+		// Expired entries (possibly kept due to grace period) delete too, but don't count.
+		if idx < b.elen() && b.entry[idx].expire >= now {
+			c++
+		}
+		_ = b.delLF(h, EventDelete)
+	}
+	return
+}
+
+// Count alive entries tagged with th tag hash.
+func (b *bucket) countByTag(th uint64) (c int, err error) {
+	if err = b.checkStatus(); err != nil {
+		return
+	}
+
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	now := b.now()
+	for _, h := range b.tags[th] {
+		if idx, ok := b.index[h]; ok && idx < b.elen() && b.entry[idx].expire >= now {
+			c++
+		}
+	}
+	return
+}
+
+func hasTag(tags []uint64, th uint64) bool {
+	for i := 0; i < len(tags); i++ {
+		if tags[i] == th {
+			return true
+		}
+	}
+	return false
+}
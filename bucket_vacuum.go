@@ -17,12 +17,15 @@ func (b *bucket) bulkVacuum() error {
 	}
 
 	var c int
-	b.svcLock()
+	b.svcLockFree()
+	stm := b.nowT()
 	defer func() {
-		if b.l() != nil {
-			b.l().Printf("bucket #%d: vacuum %d arenas", b.idx, c)
-		}
 		b.lastVac = b.nowT()
+		b.durVac = b.lastVac.Sub(stm)
+		if logEnabled(b.l(), LevelDebug) {
+			b.l().Log(LevelDebug, "vacuum arenas",
+				Field{"bucket", b.idx}, Field{"op", "vacuum"}, Field{"arenas", c}, Field{"duration", b.durVac})
+		}
 		b.svcUnlock()
 	}()
 
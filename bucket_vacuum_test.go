@@ -1,6 +1,7 @@
 package cbytecache
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -27,14 +28,39 @@ func TestVacuum(t *testing.T) {
 			t.Error(err)
 		}
 	}
-	assertSize(t, cache.Size(), CacheSize{264224768, 264222333, 2435})
+	assertSize(t, cache.Size(), CacheSize{264224768, 264222333, 2435, 0})
 	// Wait for expiration.
 	conf.Clock.Jump(time.Minute + time.Second)
 	time.Sleep(time.Millisecond * 5)
-	assertSize(t, cache.Size(), CacheSize{264224768, 0, 264224768})
+	assertSize(t, cache.Size(), CacheSize{264224768, 0, 264224768, 0})
 	// Wait for vacuum.
 	conf.Clock.Jump(time.Minute)
 	time.Sleep(time.Millisecond * 5)
-	assertSize(t, cache.Size(), CacheSize{132120576, 0, 132120576})
+	assertSize(t, cache.Size(), CacheSize{132120576, 0, 132120576, 0})
 	conf.Clock.Stop()
 }
+
+func TestVacuumForce(t *testing.T) {
+	cache, err := New(DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	done := make(chan error, 1)
+	go func() { done <- cache.Vacuum() }()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("vacuum without schedule hangs")
+	}
+
+	// Errors of buckets must come to the caller.
+	cache.buckets[0].status = bucketStatusService
+	defer func() { cache.buckets[0].status = bucketStatusActive }()
+	if err = cache.Evict(); !errors.Is(err, ErrBucketService) {
+		t.Errorf("error mismatch: need ErrBucketService got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package cbytecache
+
+// Get next entry version.
+//
+// Versions increase monotonically within the bucket, so deleted and written again entry never gets the same version.
+// Counter starts from bucket creation time in nanoseconds (see newBucket), thus versions don't restart after dump load.
+// Zero version is reserved for absent entries.
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) nextVersionLF() uint64 {
+	if b.ver++; b.ver == 0 {
+		b.ver++
+	}
+	return b.ver
+}
+
+// Set entry e by h hash only if current entry version matches version.
+//
+// Zero version means entry must not exist. Replaced entry keeps its tenant and tags, tn uses if entry doesn't exist.
+func (b *bucket) setIfVersion(e *Entry, h uint64, tn uint16, version uint64) (err error) {
+	if err = b.checkStatus(); err != nil {
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	var cur *entry
+	if idx, ok := b.index[h]; ok && idx < b.elen() {
+		if cur = &b.entry[idx]; cur.expire < b.now() {
+			cur = nil
+		}
+	}
+	switch {
+	case cur == nil && version != 0:
+		return ErrNotFound
+	case cur != nil && version == 0:
+		return ErrEntryExists
+	case cur != nil && cur.ver != version:
+		return ErrEntryVersion
+	}
+
+	tags := e.Tags
+	if cur != nil {
+		tn = cur.tn
+		if tags == nil {
+			// Delete doesn't modify tags slice, just removes it from index.
+			tags = b.tagsLF(h)
+		}
+	}
+	// Current entry keeps until the new one is written, so failed write doesn't lose it.
+	if err = b.overwriteLF(e.Key, h, e.Body, e.Expire, e.Namespace, tn); err == nil {
+		b.tagLF(h, tags)
+		b.mutateLastLF()
+	}
+	return
+}
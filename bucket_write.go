@@ -0,0 +1,237 @@
+package cbytecache
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+
+	"github.com/koykov/byteconv"
+)
+
+// Internal error to repeat optimistic write when service operation happens between space reservation and publication.
+var errEpoch = errors.New("arenas epoch changed")
+
+// Set p with tags hashes to bucket by h hash in optimistic mode.
+//
+// Bucket lock holds only to reserve arenas space and to publish the entry. Entry data copies to reserved space after
+// unlock, so writers serialize only on arenas space reservation. Reserved entry keeps in entries list as invalid
+// (like deleted) entry till publication, thus readers and service operations skip it.
+func (b *bucket) setOpt(key string, h uint64, p []byte, ns, tn uint16, tags []uint64) (err error) {
+	for {
+		if err = b.checkStatus(); err != nil {
+			return
+		}
+		if err = b.setOptOnce(key, h, p, ns, tn, tags); err != errEpoch {
+			return
+		}
+	}
+}
+
+// Single attempt of optimistic write.
+func (b *bucket) setOptOnce(key string, h uint64, p []byte, ns, tn uint16, tags []uint64) (err error) {
+	var (
+		buf [optimisticSpans][]byte
+		// Collision control data: namespace ID (optional) and key length.
+		tr  [nsSizeBytes + keySizeBytes]byte
+		trl int
+		stm = b.nowT()
+	)
+	if ns != 0 {
+		binary.LittleEndian.PutUint16(tr[:], ns)
+		trl += nsSizeBytes
+	}
+	binary.LittleEndian.PutUint16(tr[trl:], uint16(len(key)))
+	trl += keySizeBytes
+	pl := uint32(len(p) + len(key) + trl)
+
+	b.mux.Lock()
+	var exists bool
+	if idx, ok := b.index[h]; ok && idx < b.elen() {
+		// Expired entry (possibly kept due to grace period) doesn't prevent write. It deletes on publication.
+		exists = b.entry[idx].expire >= b.now()
+	}
+	var rest uint32
+	if a := b.queue.act(); a != nil {
+		rest = a.rest()
+	}
+	if exists || pl > rest+(optimisticSpans-1)*b.acap() {
+		// Existing entry needs collision check and too long entry spans too many arenas, so write them in regular way.
+		if err = b.setLF(key, h, p, 0, ns, tn); err == nil {
+			b.tagLF(h, tags)
+			b.mutateLastLF()
+		}
+		b.mux.Unlock()
+		return
+	}
+	if !b.allowOverLF(ns, tn, pl, b.entryLF(h)) {
+		b.mux.Unlock()
+		return ErrQuotaExceeded
+	}
+	spans, a, offset, err := b.reserveReclaimLF(buf[:0], h, tn, pl)
+	if err != nil {
+		b.mux.Unlock()
+		return
+	}
+	// Register invalid entry to keep entries order the same as arenas order. Unique version allows to find the entry
+	// if publication fails.
+	idx, rv := b.elen(), b.nextVersionLF()
+	b.entry = append(b.entry, entry{
+		offset: offset,
+		length: pl,
+		expire: uint32(b.config.Clock.Now().Add(b.config.ExpireInterval).Unix()),
+		aid:    a.id,
+		qp:     b.queue.ptr(),
+		ns:     ns,
+		tn:     tn,
+		ver:    rv,
+	})
+	b.size.snap(snapSet, pl)
+	epoch := atomic.LoadUint32(&b.epoch)
+	atomic.AddInt32(&b.writers, 1)
+	b.mux.Unlock()
+
+	w := spanWriter{spans: spans}
+	w.write(p)
+	w.write(byteconv.S2B(key))
+	w.write(tr[:trl])
+	atomic.AddInt32(&b.writers, -1)
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if atomic.LoadUint32(&b.epoch) != epoch {
+		// Service operation might move or evict reserved entry.
+		b.dropReservedLF(idx, rv)
+		b.mw().Set(b.ids, b.nowT().Sub(stm))
+		b.mw().Del(b.ids)
+		return errEpoch
+	}
+	// Concurrent writer might publish entry with the same key or take the rest of quota.
+	if idx1, ok := b.index[h]; ok && idx1 < b.elen() && b.entry[idx1].expire >= b.now() {
+		err = ErrEntryExists
+	} else if !b.allowOverLF(ns, tn, pl, b.entryLF(h)) {
+		err = ErrQuotaExceeded
+	}
+	if err != nil {
+		b.dropReservedLF(idx, rv)
+		b.mw().Set(b.ids, b.nowT().Sub(stm))
+		b.mw().Del(b.ids)
+		return
+	}
+	_ = b.delLF(h, EventExpire)
+	e := &b.entry[idx]
+	e.hash, e.ver = h, b.nextVersionLF()
+	b.index[h] = idx
+	b.delMissingLF(h)
+	b.accountLF(e)
+	b.tagLF(h, tags)
+	b.mutateLF(e)
+	b.mw().Set(b.ids, b.nowT().Sub(stm))
+	return ErrOK
+}
+
+// Drop reserved entry with rv version that failed to publish.
+//
+// Entry keeps invalid till eviction like deleted entries, but its size releases immediately. Service operation might
+// move the entry towards the head or evict it, so search starts from idx position. It works in lock-free mode thus
+// need to guarantee thread-safety outside.
+func (b *bucket) dropReservedLF(idx uint32, rv uint64) {
+	el := b.elen()
+	if el == 0 {
+		return
+	}
+	if idx >= el {
+		idx = el - 1
+	}
+	for i := int(idx); i >= 0; i-- {
+		if e := &b.entry[i]; e.invalid() && e.ver == rv {
+			b.size.snap(snapEvict, e.length)
+			// Zero length prevents double release on eviction.
+			e.length = 0
+			return
+		}
+	}
+}
+
+// Reserve arenas space of length l and collect memory spans of reserved space to dst.
+//
+// Returns starting arena and offset of reserved space in it. Spans count depends on arenas count the space shares
+// among, dst grows if needed. It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) reserveLF(dst [][]byte, l uint32) ([][]byte, *arena, uint32, error) {
+	// Init alloc.
+	if b.queue.len() == 0 {
+		a := b.queue.alloc(nil, b.acap())
+		b.queue.setHead(a).setAct(a)
+		b.mw().Alloc(b.ids, b.acap())
+		b.size.snap(snapAlloc, b.acap())
+	}
+	// Get current arena.
+	a := b.queue.act()
+	startArena := a
+	arenaOffset, arenaRest := a.offset(), a.rest()
+	if arenaRest >= l {
+		// Arena has enough space to write the entry.
+		return append(dst, a.reserve(l)), startArena, arenaOffset, ErrOK
+	}
+	// Arena hasn't enough space - need share entry among arenas.
+	// New arenas allocation will need, so check if it's possible.
+	_, full, _ := b.queue.stat()
+	need := (l - arenaRest + b.acap() - 1) / b.acap()
+	if b.maxCap > 0 && uint64(full+need)*uint64(b.acap()) > uint64(b.maxCap) {
+		// Allocation denied, thus stop write at all.
+		b.mw().NoSpace(b.ids)
+		return dst, nil, 0, ErrNoSpace
+	}
+	// Share entry among current and next arenas.
+	mustWrite := arenaRest
+	for {
+		// Reserve entry bytes that fits to arena free space.
+		dst = append(dst, a.reserve(mustWrite))
+		if l -= mustWrite; l == 0 {
+			// All entry bytes reserved.
+			break
+		}
+		// Switch to the next arena.
+		prev := a
+		a = a.next()
+		b.queue.setAct(a)
+		b.mw().Fill(b.ids, b.acap())
+		// Alloc new arena if needed.
+		if a == nil {
+			a = b.queue.alloc(prev, b.acap())
+			b.mw().Alloc(b.ids, b.acap())
+			prev.setNext(a)
+			b.queue.setAct(a).setTail(a)
+			b.size.snap(snapAlloc, b.acap())
+		}
+		// Calculate rest of bytes to reserve.
+		mustWrite = umin32(l, b.acap())
+	}
+	return dst, startArena, arenaOffset, ErrOK
+}
+
+// Reserve arenas space like reserveLF(), but if bucket has no space reclaims it from over-share tenant (see
+// reclaimSpaceLF) on behalf of tenant tn and tries again.
+func (b *bucket) reserveReclaimLF(dst [][]byte, h uint64, tn uint16, l uint32) ([][]byte, *arena, uint32, error) {
+	spans, a, offset, err := b.reserveLF(dst, l)
+	if err == ErrNoSpace && b.reclaimSpaceLF(h, tn, l) {
+		spans, a, offset, err = b.reserveLF(dst, l)
+	}
+	return spans, a, offset, err
+}
+
+// Sequential writer over arenas memory spans.
+type spanWriter struct {
+	spans [][]byte
+	// Current span and offset in it.
+	i, off int
+}
+
+func (w *spanWriter) write(p []byte) {
+	for len(p) > 0 {
+		n := copy(w.spans[w.i][w.off:], p)
+		p = p[n:]
+		if w.off += n; w.off == len(w.spans[w.i]) {
+			w.i, w.off = w.i+1, 0
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cbytecache
 import (
 	"fmt"
 	"io"
+	"math"
 	"sync"
 	"sync/atomic"
 )
@@ -12,6 +13,11 @@ type Cache struct {
 	config  *Config
 	status  uint32
 	buckets []*bucket
+	lg      loadGroup
+	rp      *refreshPool
+	al      *asyncListener
+	nsr     nsRegistry
+	tnr     tenantRegistry
 
 	maxEntrySize uint32
 }
@@ -55,6 +61,10 @@ func New(conf *Config) (*Cache, error) {
 	if conf.VacuumInterval > 0 && conf.VacuumInterval <= conf.EvictInterval {
 		return nil, ErrVacuumDur
 	}
+	// Check refresh window.
+	if conf.Refresher != nil && conf.RefreshWindow <= 0 {
+		return nil, ErrRefreshWindow
+	}
 	if r := conf.VacuumRatio; r <= 0 || r > 1 {
 		conf.VacuumRatio = VacuumRatioModerate
 	}
@@ -63,6 +73,10 @@ func New(conf *Config) (*Cache, error) {
 		conf.MetricsWriter = &DummyMetrics{}
 	}
 
+	if conf.StructLogger == nil && conf.Logger != nil {
+		conf.StructLogger = NewPrintfLogger(conf.Logger, conf.LogLevel)
+	}
+
 	if conf.Clock == nil {
 		conf.Clock = &NativeClock{}
 	}
@@ -77,41 +91,75 @@ func New(conf *Config) (*Cache, error) {
 
 		maxEntrySize: uint32(bktCap),
 	}
+	c.lg.calls = make(map[uint64]*loadCall)
+	if conf.Refresher != nil {
+		if conf.RefreshWorkers == 0 {
+			conf.RefreshWorkers = defaultRefreshWorkers
+		}
+		if conf.RefreshQueueSize == 0 {
+			conf.RefreshQueueSize = defaultRefreshQueueSize
+		}
+		c.rp = newRefreshPool(c, conf.RefreshWorkers, conf.RefreshQueueSize)
+	}
+	if conf.ExpireListener != nil && conf.ExpireListenerAsync {
+		if conf.ExpireListenerBuffer == 0 {
+			conf.ExpireListenerBuffer = defaultExpireListenerBuffer
+		}
+		if conf.ExpireListenerWorkers == 0 {
+			conf.ExpireListenerWorkers = defaultExpireListenerWorkers
+		}
+		c.al = newAsyncListener(conf)
+	}
+	if conf.MissingLimit == 0 {
+		conf.MissingLimit = defaultMissingLimit
+	}
+	if conf.TenantLimit == 0 {
+		conf.TenantLimit = defaultTenantLimit
+	}
+	if conf.TenantLimit > math.MaxUint16 {
+		conf.TenantLimit = math.MaxUint16
+	}
+	c.tnr.limit = int(conf.TenantLimit)
 	c.buckets = make([]*bucket, conf.Buckets)
 	for i := range c.buckets {
 		c.buckets[i] = newBucket(uint32(i), conf, bktCap)
+		c.buckets[i].rp = c.rp
+		c.buckets[i].al = c.al
+	}
+
+	// Forced eviction and vacuum (see Evict and Vacuum) need workers even without schedule.
+	if conf.EvictWorkers == 0 {
+		conf.EvictWorkers = defaultEvictWorkers
+	}
+	if conf.VacuumWorkers == 0 {
+		conf.VacuumWorkers = defaultVacuumWorkers
 	}
 
 	// Register evict schedule job.
 	if conf.EvictInterval > 0 {
-		if conf.EvictWorkers == 0 {
-			conf.EvictWorkers = defaultEvictWorkers
-		}
 		conf.Clock.Schedule(conf.EvictInterval, func() {
-			if err := c.evict(); err != nil && c.l() != nil {
-				c.l().Printf("eviction failed with error %s\n", err.Error())
+			if err := c.evict(false); err != nil && logEnabled(c.l(), LevelError) {
+				c.l().Log(LevelError, "eviction failed", Field{"op", "evict"}, Field{"error", err})
 			}
 		})
 	}
 	// Register vacuum schedule job.
 	if conf.VacuumInterval > 0 {
-		if conf.VacuumWorkers == 0 {
-			conf.VacuumWorkers = defaultVacuumWorkers
-		}
 		conf.Clock.Schedule(conf.VacuumInterval, func() {
-			if err := c.vacuum(); err != nil && c.l() != nil {
-				c.l().Printf("vacuum failed with error %s\n", err.Error())
+			if err := c.vacuum(); err != nil && logEnabled(c.l(), LevelError) {
+				c.l().Log(LevelError, "vacuum failed", Field{"op", "vacuum"}, Field{"error", err})
 			}
 		})
 	}
 	// Register dump schedule job.
+	if conf.DumpWriteWorkers == 0 {
+		// Forced dumps (see Dump and DumpTo) need workers even without schedule.
+		conf.DumpWriteWorkers = defaultDumpWriteWorkers
+	}
 	if conf.DumpWriter != nil && conf.DumpInterval > 0 {
-		if conf.DumpWriteWorkers == 0 {
-			conf.DumpWriteWorkers = defaultDumpWriteWorkers
-		}
 		conf.Clock.Schedule(conf.DumpInterval, func() {
-			if err := c.dump(); err != nil && c.l() != nil {
-				c.l().Printf("dump write failed with error %s\n", err.Error())
+			if err := c.dump(); err != nil && logEnabled(c.l(), LevelError) {
+				c.l().Log(LevelError, "dump write failed", Field{"op", "dump"}, Field{"error", err})
 			}
 		})
 	}
@@ -125,13 +173,15 @@ func New(conf *Config) (*Cache, error) {
 			conf.DumpReadBuffer = conf.DumpReadWorkers
 		}
 		fn := func() {
+			stm := conf.Clock.Now()
 			lc, err := c.load()
-			if c.l() != nil {
-				if err != nil {
-					c.l().Printf("dump read failed with error %s\n", err.Error())
-				} else {
-					c.l().Printf("read %d entries from dump\n", lc)
+			if err != nil {
+				if logEnabled(c.l(), LevelError) {
+					c.l().Log(LevelError, "dump read failed", Field{"op", "load"}, Field{"error", err})
 				}
+			} else if logEnabled(c.l(), LevelInfo) {
+				c.l().Log(LevelInfo, "read entries from dump",
+					Field{"op", "load"}, Field{"count", lc}, Field{"duration", conf.Clock.Now().Sub(stm)})
 			}
 		}
 		if conf.DumpReadAsync {
@@ -156,22 +206,19 @@ func (c *Cache) SetMarshallerTo(key string, m MarshallerTo) error {
 
 // Internal bytes setter.
 func (c *Cache) set(key string, data []byte) error {
-	if len(key) > MaxKeySize {
-		return ErrKeyTooBig
-	}
 	if err := c.checkCache(cacheStatusActive); err != nil {
 		return err
 	}
-	dl := uint32(len(data))
-	if dl == 0 {
-		return ErrEntryEmpty
+	if err := c.checkEntry(key, uint32(len(data))); err != nil {
+		return err
 	}
-	if c.maxEntrySize > 0 && dl > c.maxEntrySize {
-		return ErrEntryTooBig
+	tn, err := c.tenantOf(key)
+	if err != nil {
+		return err
 	}
 	h := c.config.Hasher.Sum64(key)
 	bkt := c.buckets[h%uint64(c.config.Buckets)]
-	return bkt.set(key, h, data)
+	return bkt.set(key, h, data, 0, tn, nil)
 }
 
 // Internal marshaller object setter.
@@ -189,9 +236,13 @@ func (c *Cache) setm(key string, m MarshallerTo) error {
 	if ml > c.maxEntrySize {
 		return ErrEntryTooBig
 	}
+	tn, err := c.tenantOf(key)
+	if err != nil {
+		return err
+	}
 	h := c.config.Hasher.Sum64(key)
 	bkt := c.buckets[h%uint64(c.config.Buckets)]
-	return bkt.setm(key, h, m)
+	return bkt.setm(key, h, m, tn)
 }
 
 // Get gets entry bytes by key.
@@ -209,6 +260,18 @@ func (c *Cache) GetTo(dst []byte, key string) ([]byte, error) {
 	return bkt.get(dst, h, false)
 }
 
+// GetEntryTo gets entry with key, body and expire timestamp. Body will write to dst.
+//
+// Entry key and body point to dst and stay valid until dst reuse.
+func (c *Cache) GetEntryTo(dst []byte, key string) (Entry, error) {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return Entry{Body: dst}, err
+	}
+	h := c.config.Hasher.Sum64(key)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.getEntry(dst, h, false, false)
+}
+
 // Extract gets entry bytes by key and remove entry afterward.
 func (c *Cache) Extract(key string) ([]byte, error) {
 	return c.ExtractTo(nil, key)
@@ -234,6 +297,12 @@ func (c *Cache) Delete(key string) error {
 	return bkt.del(h)
 }
 
+// MaxEntrySize returns maximum size of entry (including key) the cache may store.
+// Zero means the cache has no limit.
+func (c *Cache) MaxEntrySize() MemorySize {
+	return MemorySize(c.maxEntrySize)
+}
+
 // Size returns cache size snapshot. Contains total, used and free sizes.
 func (c *Cache) Size() (r CacheSize) {
 	_ = c.buckets[len(c.buckets)-1]
@@ -242,6 +311,7 @@ func (c *Cache) Size() (r CacheSize) {
 		r.t += MemorySize(t)
 		r.u += MemorySize(u)
 		r.f += MemorySize(f)
+		r.m += uint64(c.buckets[i].size.missingSnapshot())
 	}
 	return
 }
@@ -261,16 +331,56 @@ func (c *Cache) Release() error {
 // You cannot use cache after that.
 func (c *Cache) Close() error {
 	atomic.StoreUint32(&c.status, cacheStatusClosed)
-	if err := c.Release(); err != nil {
-		return err
+	if c.rp != nil {
+		c.rp.stop()
 	}
+	// Run all cleanup steps even if release fails, so clock and listener workers don't leak. The first error returns.
+	err := c.Release()
 	c.config.Clock.Stop()
-	return ErrOK
+	if c.al != nil {
+		c.al.stop()
+	}
+	// Metrics writer may hold external resources (like registered collectors).
+	if cl, ok := c.config.MetricsWriter.(io.Closer); ok {
+		if err1 := cl.Close(); err == nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// Evict performs force eviction of expired cache data.
+func (c *Cache) Evict() error {
+	return c.evict(true)
+}
+
+// Vacuum performs force vacuum of cache space.
+func (c *Cache) Vacuum() error {
+	return c.vacuum()
+}
+
+// Dump performs force dump of all cache data to DumpWriter.
+func (c *Cache) Dump() error {
+	if c.config.DumpWriter == nil {
+		return ErrNoDumpWriter
+	}
+	return c.dump()
+}
+
+// DumpTo performs force dump of all cache data to given writer w.
+//
+// Useful to make a snapshot of the cache independent of DumpWriter, e.g. for initial sync of replicas.
+// Unlike scheduled dump, buckets under service operation aren't skipped.
+func (c *Cache) DumpTo(w DumpWriter) error {
+	if w == nil {
+		return ErrNoDumpWriter
+	}
+	return c.dumpTo(w, true)
 }
 
 // Evict expired cache data.
-func (c *Cache) evict() error {
-	return c.bulkExec(c.config.EvictWorkers, "eviction", func(b *bucket) error { return b.bulkEvict() })
+func (c *Cache) evict(force bool) error {
+	return c.bulkExec(c.config.EvictWorkers, "eviction", func(b *bucket) error { return b.bulkEvict(force) })
 }
 
 // Vacuum free cache space.
@@ -283,10 +393,17 @@ func (c *Cache) dump() error {
 	if c.config.DumpWriter == nil {
 		return ErrOK
 	}
-	if err := c.bulkExec(c.config.DumpWriteWorkers, "dump", func(b *bucket) error { return b.bulkDump() }); err != nil {
-		return err
+	return c.dumpTo(c.config.DumpWriter, false)
+}
+
+// Dump all cache data to writer w.
+func (c *Cache) dumpTo(w DumpWriter, wait bool) error {
+	// Flush entries of succeeded buckets even if some buckets failed.
+	err := c.bulkExec(c.config.DumpWriteWorkers, "dump", func(b *bucket) error { return b.bulkDump(w, wait) })
+	if ferr := w.Flush(); err == nil {
+		err = ferr
 	}
-	return c.config.DumpWriter.Flush()
+	return err
 }
 
 // Load dumped data.
@@ -303,10 +420,16 @@ func (c *Cache) load() (int, error) {
 					if !ok {
 						return
 					}
-					h := c.config.Hasher.Sum64(e.Key)
+					tn, err := c.tenantOf(e.Key)
+					if err != nil {
+						continue
+					}
+					h := nsHash(c.config.Hasher.Sum64(e.Key), e.Namespace)
 					bkt := c.buckets[h%uint64(c.config.Buckets)]
 					bkt.svcLock()
-					_ = bkt.setLF(e.Key, h, e.Body, e.Expire)
+					if bkt.setLF(e.Key, h, e.Body, e.Expire, e.Namespace, tn) == nil {
+						bkt.tagLF(h, e.Tags)
+					}
 					bkt.svcUnlock()
 					c.mw().Load(bkt.ids)
 				}
@@ -319,8 +442,8 @@ func (c *Cache) load() (int, error) {
 		e, err := c.config.DumpReader.Read()
 		if err != nil {
 			close(stream)
-			if err != io.EOF && c.l() != nil {
-				c.l().Printf("dump load interrupt due to error: %s", err.Error())
+			if err != io.EOF && logEnabled(c.l(), LevelError) {
+				c.l().Log(LevelError, "dump load interrupted", Field{"op", "load"}, Field{"error", err})
 			}
 			break
 		}
@@ -345,7 +468,13 @@ func (c *Cache) bulkExecWS(workers uint, op string, fn func(*bucket) error, allo
 	}
 	count := umin32(uint32(workers), uint32(c.config.Buckets))
 	bucketQueue := make(chan uint, count)
-	var wg sync.WaitGroup
+	var (
+		wg  sync.WaitGroup
+		mux sync.Mutex
+		// First bucket error and count of failed buckets.
+		ferr error
+		fc   uint
+	)
 
 	for i := uint32(0); i < count; i++ {
 		wg.Add(1)
@@ -354,8 +483,16 @@ func (c *Cache) bulkExecWS(workers uint, op string, fn func(*bucket) error, allo
 			for {
 				if idx, ok := <-bucketQueue; ok {
 					bkt := c.buckets[idx]
-					if err := fn(bkt); err != nil && c.l() != nil {
-						c.l().Printf("bucket #%d: %s failed with error '%s'\n", idx, op, err.Error())
+					if err := fn(bkt); err != nil {
+						if logEnabled(c.l(), LevelError) {
+							c.l().Log(LevelError, "bucket operation failed",
+								Field{"bucket", idx}, Field{"op", op}, Field{"error", err})
+						}
+						mux.Lock()
+						if fc++; ferr == nil {
+							ferr = err
+						}
+						mux.Unlock()
 					}
 					continue
 				}
@@ -375,6 +512,23 @@ func (c *Cache) bulkExecWS(workers uint, op string, fn func(*bucket) error, allo
 
 	wg.Wait()
 
+	if ferr != nil {
+		return fmt.Errorf("%s failed on %d of %d buckets: %w", op, fc, c.config.Buckets, ferr)
+	}
+	return ErrOK
+}
+
+// Check entry key and body length.
+func (c *Cache) checkEntry(key string, dl uint32) error {
+	if len(key) > MaxKeySize {
+		return ErrKeyTooBig
+	}
+	if dl == 0 {
+		return ErrEntryEmpty
+	}
+	if c.maxEntrySize > 0 && dl > c.maxEntrySize {
+		return ErrEntryTooBig
+	}
 	return ErrOK
 }
 
@@ -391,14 +545,19 @@ func (c *Cache) checkCache(allow uint32) error {
 	return nil
 }
 
+// Return timestamp as uint32 value.
+func (c *Cache) now() uint32 {
+	return uint32(c.config.Clock.Now().Unix())
+}
+
 // Shorthand metrics writer method.
 func (c *Cache) mw() MetricsWriter {
 	return c.config.MetricsWriter
 }
 
 // Shorthand logger method.
-func (c *Cache) l() Logger {
-	return c.config.Logger
+func (c *Cache) l() StructLogger {
+	return c.config.StructLogger
 }
 
 func umin32(a, b uint32) uint32 {
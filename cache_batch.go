@@ -0,0 +1,74 @@
+package cbytecache
+
+// GetMany gets entries of keys to the batch.
+//
+// All keys hash at first and group by buckets, thus every bucket locks once per batch. Per-key bodies and errors are
+// available in batch in keys order. Returned error reports only cache-level problems.
+func (c *Cache) GetMany(batch *Batch, keys []string) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	batch.Reset()
+	for i := 0; i < len(keys); i++ {
+		h := c.config.Hasher.Sum64(keys[i])
+		batch.add(h, uint(h%uint64(c.config.Buckets)), nil)
+	}
+	c.batchExec(batch, func(bkt *bucket, pos []int) { bkt.getMany(batch, pos) })
+	return ErrOK
+}
+
+// SetMany sets entries to the cache.
+//
+// Entries expire timestamps ignore. Per-key errors are available in batch in entries order.
+// Returned error reports only cache-level problems.
+func (c *Cache) SetMany(batch *Batch, entries []Entry) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	batch.Reset()
+	for i := 0; i < len(entries); i++ {
+		e := &entries[i]
+		if err := c.checkEntry(e.Key, uint32(len(e.Body))); err != nil {
+			batch.add(0, 0, err)
+			continue
+		}
+		tn, err := c.tenantOf(e.Key)
+		if err != nil {
+			batch.add(0, 0, err)
+			continue
+		}
+		h := c.config.Hasher.Sum64(e.Key)
+		batch.add(h, uint(h%uint64(c.config.Buckets)), nil)
+		batch.item[len(batch.item)-1].tenant = tn
+	}
+	c.batchExec(batch, func(bkt *bucket, pos []int) { bkt.setMany(batch, entries, pos) })
+	return ErrOK
+}
+
+// DeleteMany removes entries of keys from the cache.
+//
+// Per-key errors are available in batch in keys order. Returned error reports only cache-level problems.
+func (c *Cache) DeleteMany(batch *Batch, keys []string) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	batch.Reset()
+	for i := 0; i < len(keys); i++ {
+		h := c.config.Hasher.Sum64(keys[i])
+		batch.add(h, uint(h%uint64(c.config.Buckets)), nil)
+	}
+	c.batchExec(batch, func(bkt *bucket, pos []int) { bkt.delMany(batch, pos) })
+	return ErrOK
+}
+
+// Group batch items by buckets and call fn for every affected bucket.
+func (c *Cache) batchExec(batch *Batch, fn func(bkt *bucket, pos []int)) {
+	bounds := batch.group(c.config.Buckets)
+	for i := uint(0); i < c.config.Buckets; i++ {
+		lo, hi := bounds[i], bounds[i+1]
+		if lo == hi {
+			continue
+		}
+		fn(c.buckets[i], batch.order[lo:hi])
+	}
+}
@@ -0,0 +1,104 @@
+package cbytecache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/koykov/hash/fnv"
+)
+
+func TestBatch(t *testing.T) {
+	const count = 200
+
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, count)
+	entries := make([]Entry, 0, count)
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("key%d", i)
+		keys = append(keys, key)
+		entries = append(entries, Entry{Key: key, Body: getEntryBody(i)})
+	}
+	// Empty entry must fail individually.
+	entries[5].Body = nil
+
+	var batch Batch
+	t.Run("set", func(t *testing.T) {
+		if err = cache.SetMany(&batch, entries); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < batch.Len(); i++ {
+			if err := batch.Err(i); i == 5 && err != ErrEntryEmpty || i != 5 && err != nil {
+				t.Errorf("unexpected error of key #%d: %v", i, err)
+			}
+		}
+	})
+	t.Run("get", func(t *testing.T) {
+		if err = cache.GetMany(&batch, keys); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < batch.Len(); i++ {
+			body, err := batch.Get(i)
+			if i == 5 {
+				if err != ErrNotFound {
+					t.Errorf("key #%d: need not found error, got %v", i, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			assertBytes(t, getEntryBody(i), body)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		if err = cache.DeleteMany(&batch, keys[:count/2]); err != nil {
+			t.Fatal(err)
+		}
+		_ = cache.GetMany(&batch, keys)
+		for i := 0; i < batch.Len(); i++ {
+			if _, err := batch.Get(i); (i < count/2 || i == 5) != (err == ErrNotFound) {
+				t.Errorf("unexpected error of key #%d: %v", i, err)
+			}
+		}
+	})
+	_ = cache.Close()
+}
+
+func BenchmarkBatch(b *testing.B) {
+	const count = 200
+
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	cache, err := New(conf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("key%d", i)
+		keys = append(keys, key)
+		_ = cache.Set(key, getEntryBody(i))
+	}
+	b.Run("get many", func(b *testing.B) {
+		b.ReportAllocs()
+		var batch Batch
+		for i := 0; i < b.N; i++ {
+			_ = cache.GetMany(&batch, keys)
+		}
+	})
+	b.Run("get loop", func(b *testing.B) {
+		b.ReportAllocs()
+		var dst []byte
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < count; j++ {
+				dst, _ = cache.GetTo(dst[:0], keys[j])
+			}
+		}
+	})
+	_ = cache.Close()
+}
@@ -0,0 +1,103 @@
+package cbytecache
+
+import (
+	"errors"
+	"sync"
+)
+
+// Loader is a function that loads entry body on cache miss.
+//
+// Return error wraps ErrNotFound to report that entry doesn't exist in the backend. Such results may be cached for a
+// short time, see Config.LoadNegativeExpire.
+type Loader func() ([]byte, error)
+
+// Group of in-flight loads. Coalesces concurrent loads of the same key hash.
+type loadGroup struct {
+	mux   sync.Mutex
+	calls map[uint64]*loadCall
+}
+
+// Single in-flight load.
+type loadCall struct {
+	wg   sync.WaitGroup
+	body []byte
+	err  error
+}
+
+// GetOrLoad gets entry bytes by key or loads it using load function on miss.
+//
+// Concurrent misses of the same key call load only once, all callers get the same result.
+func (c *Cache) GetOrLoad(key string, load Loader) ([]byte, error) {
+	return c.GetToOrLoad(nil, key, load)
+}
+
+// GetToOrLoad gets entry bytes to dst or loads it using load function on miss.
+//
+// Concurrent misses of the same key call load only once, all callers get the same result.
+func (c *Cache) GetToOrLoad(dst []byte, key string, load Loader) ([]byte, error) {
+	if load == nil {
+		return dst, ErrNoLoader
+	}
+	var err error
+	off := len(dst)
+	if dst, err = c.GetTo(dst, key); err == nil {
+		return dst, err
+	}
+	if err == ErrMissing {
+		// Negative result of previous load.
+		return dst, ErrNotFound
+	}
+	// Bucket maintenance doesn't prevent loading.
+	if err != ErrNotFound && err != ErrBucketService {
+		return dst, err
+	}
+	dst = dst[:off]
+	h := c.config.Hasher.Sum64(key)
+
+	lg := &c.lg
+	lg.mux.Lock()
+	if call, ok := lg.calls[h]; ok {
+		// Another load in progress, wait for it.
+		lg.mux.Unlock()
+		call.wg.Wait()
+		return append(dst, call.body...), call.err
+	}
+	call := &loadCall{}
+	call.wg.Add(1)
+	lg.calls[h] = call
+	lg.mux.Unlock()
+
+	defer func() {
+		lg.mux.Lock()
+		delete(lg.calls, h)
+		lg.mux.Unlock()
+		call.wg.Done()
+	}()
+
+	// Entry may be loaded by previous call that finished after the miss.
+	if dst, err = c.GetTo(dst, key); err == nil {
+		// Waiters may read body after return, so copy it from dst.
+		call.body = append([]byte(nil), dst[off:]...)
+		return dst, err
+	}
+	dst = dst[:off]
+	if err == ErrMissing {
+		call.err = ErrNotFound
+		return dst, call.err
+	}
+
+	// Waiters get this error if load panics, panic itself goes to the caller.
+	call.err = ErrLoadPanic
+	if call.body, call.err = load(); call.err != nil {
+		if errors.Is(call.err, ErrNotFound) && c.config.LoadNegativeExpire > 0 {
+			_ = c.SetMissing(key, c.config.LoadNegativeExpire)
+		}
+		return dst, call.err
+	}
+	// Concurrent Set may write the entry before, so ErrEntryExists is OK. Other errors (no space, ...) don't affect
+	// the result since entry body is loaded.
+	if err = c.Set(key, call.body); err != nil && err != ErrEntryExists && logEnabled(c.l(), LevelWarn) {
+		c.l().Log(LevelWarn, "loaded entry set failed", Field{"op", "load"}, Field{"key", key}, Field{"error", err})
+	}
+	return append(dst, call.body...), ErrOK
+}
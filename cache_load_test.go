@@ -0,0 +1,123 @@
+package cbytecache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	t.Run("coalesce", func(t *testing.T) {
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			calls uint32
+			wg    sync.WaitGroup
+			start = make(chan struct{})
+		)
+		load := func() ([]byte, error) {
+			atomic.AddUint32(&calls, 1)
+			time.Sleep(time.Millisecond * 10)
+			return getEntryBody(0), nil
+		}
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				body, err := cache.GetOrLoad("foobar", load)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				assertBytes(t, getEntryBody(0), body)
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if c := atomic.LoadUint32(&calls); c != 1 {
+			t.Errorf("loader calls mismatch: need 1 got %d", c)
+		}
+		body, err := cache.Get("foobar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBytes(t, getEntryBody(0), body)
+		_ = cache.Close()
+	})
+	t.Run("negative", func(t *testing.T) {
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf.Clock = clock.NewClock()
+		conf.LoadNegativeExpire = time.Second * 5
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var calls int
+		load := func() ([]byte, error) {
+			calls++
+			return nil, fmt.Errorf("backend: %w", ErrNotFound)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err = cache.GetOrLoad("foobar", load); err == nil {
+				t.Error("error expected")
+			}
+		}
+		if calls != 1 {
+			t.Errorf("loader calls mismatch: need 1 got %d", calls)
+		}
+		conf.Clock.Jump(time.Second * 6)
+		_, _ = cache.GetOrLoad("foobar", load)
+		if calls != 2 {
+			t.Errorf("loader calls mismatch after negative expire: need 2 got %d", calls)
+		}
+		_ = cache.Close()
+	})
+	t.Run("panic", func(t *testing.T) {
+		cache, err := New(DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache.Close() }()
+		var (
+			wg      sync.WaitGroup
+			started = make(chan struct{})
+			release = make(chan struct{})
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if recover() == nil {
+					t.Error("panic must pass to the caller")
+				}
+			}()
+			_, _ = cache.GetOrLoad("foobar", func() ([]byte, error) {
+				close(started)
+				<-release
+				panic("backend failure")
+			})
+		}()
+		<-started
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := cache.GetOrLoad("foobar", func() ([]byte, error) { return getEntryBody(0), nil })
+			// Waiter may come after panic and load the entry itself.
+			if err != ErrLoadPanic && (err != nil || len(body) == 0) {
+				t.Errorf("waiter result mismatch: %q %v", body, err)
+			}
+		}()
+		time.Sleep(time.Millisecond * 10)
+		close(release)
+		wg.Wait()
+	})
+}
@@ -0,0 +1,24 @@
+package cbytecache
+
+import "time"
+
+// SetMissing marks key as known missing (e.g. backend doesn't contain it) for ttl duration.
+//
+// Get methods return ErrMissing for such keys instead of ErrNotFound, that allows to distinguish "known missing" from
+// "not cached" entries. Missing entries store only in bucket index without arena payload. Existing entry with the
+// same key deletes, following write of the key removes missing mark.
+// Zero ttl means default expiration (see Config.ExpireInterval).
+func (c *Cache) SetMissing(key string, ttl time.Duration) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if len(key) > MaxKeySize {
+		return ErrKeyTooBig
+	}
+	if ttl <= 0 {
+		ttl = c.config.ExpireInterval
+	}
+	h := c.config.Hasher.Sum64(key)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.setMissing(h, uint32(c.config.Clock.Now().Add(ttl).Unix()))
+}
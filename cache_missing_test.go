@@ -0,0 +1,95 @@
+package cbytecache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestMissing(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	if _, err = cache.Get("foo"); err != ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+	size := cache.Size()
+	if err = cache.SetMissing("foo", time.Second*5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cache.Get("foo"); err != ErrMissing {
+		t.Errorf("error mismatch: need ErrMissing got %v", err)
+	}
+	size1 := cache.Size()
+	if size1.Missing() != 1 || size1.Used() != size.Used() || size1.Total() != size.Total() {
+		t.Errorf("size mismatch: %s (missing %d)", size1, size1.Missing())
+	}
+
+	// Write removes missing mark.
+	if err = cache.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := cache.Get("foo"); err != nil || string(b) != "bar" {
+		t.Errorf("get mismatch: %s %v", b, err)
+	}
+	if cache.Size().Missing() != 0 {
+		t.Error("missing count must be reset after write")
+	}
+
+	// Missing mark deletes existing entry.
+	_ = cache.SetMissing("foo", 0)
+	if _, err = cache.Get("foo"); err != ErrMissing {
+		t.Errorf("error mismatch: need ErrMissing got %v", err)
+	}
+	_ = cache.Delete("foo")
+	if _, err = cache.Get("foo"); err != ErrNotFound {
+		t.Errorf("error mismatch after delete: need ErrNotFound got %v", err)
+	}
+
+	// Expiration.
+	_ = cache.SetMissing("bar", time.Second*5)
+	conf.Clock.Jump(time.Second * 6)
+	if _, err = cache.Get("bar"); err != ErrNotFound {
+		t.Errorf("error mismatch after expire: need ErrNotFound got %v", err)
+	}
+	_ = cache.Evict()
+	if n := cache.Size().Missing(); n != 0 {
+		t.Errorf("missing count mismatch after evict: need 0 got %d", n)
+	}
+}
+
+func TestMissingLimit(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	conf.Buckets = 1
+	conf.MissingLimit = 100
+	// Metrics writer without missing methods.
+	conf.MetricsWriter = struct{ MetricsWriter }{DummyMetrics{}}
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	for i := 0; i < 1000; i++ {
+		if err = cache.SetMissing(fmt.Sprintf("key%d", i), time.Second*time.Duration(i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Limit reach frees the batch of marks.
+	if m := cache.Size().Missing(); m > 100 || m <= 100-100/8 {
+		t.Errorf("missing count mismatch: need (87, 100] got %d", m)
+	}
+	// The latest mark always survives.
+	if _, err = cache.Get("key999"); err != ErrMissing {
+		t.Errorf("error mismatch: need ErrMissing got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package cbytecache
+
+// ScanKeys calls fn for keys of all alive entries of bucket with index i. Returns index of the next bucket to scan or
+// zero if i is the last bucket, so full iteration starts from zero and stops when zero returns.
+//
+// Bucket holds read lock during the scan, thus fn must be fast and must not call the cache. Key is valid only during fn
+// call. Iteration doesn't make snapshot of the whole cache, so entries written to already scanned buckets will not
+// return.
+func (c *Cache) ScanKeys(i uint, fn func(key string)) (next uint, err error) {
+	if err = c.checkCache(cacheStatusActive); err != nil {
+		return
+	}
+	if i >= uint(len(c.buckets)) {
+		return
+	}
+	if err = c.buckets[i].scanKeys(fn); err != nil {
+		return
+	}
+	if next = i + 1; next == uint(len(c.buckets)) {
+		next = 0
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package cbytecache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestScanKeys(t *testing.T) {
+	const count = 1000
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	for i := 0; i < count; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i))
+	}
+	_ = cache.Delete("key0")
+	scan := func() map[string]int {
+		keys := make(map[string]int)
+		var i uint
+		for {
+			// Key is valid only during the call, so copy it.
+			if i, err = cache.ScanKeys(i, func(key string) { keys[string([]byte(key))]++ }); err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				return keys
+			}
+		}
+	}
+	if keys := scan(); len(keys) != count-1 || keys["key0"] != 0 || keys["key1"] != 1 {
+		t.Errorf("keys count mismatch: need %d got %d", count-1, len(keys))
+	}
+
+	conf.Clock.Jump(time.Second * 30)
+	_ = cache.Set("fresh", []byte("foobar"))
+	conf.Clock.Jump(time.Second * 40)
+	// Only fresh entry is alive.
+	if keys := scan(); len(keys) != 1 || keys["fresh"] != 1 {
+		t.Errorf("keys mismatch: %v", keys)
+	}
+}
@@ -0,0 +1,100 @@
+package cbytecache
+
+// GetStale gets entry bytes by key considering grace period.
+//
+// Expired entry within grace period (see Config.StaleInterval) returns together with ErrStale error.
+func (c *Cache) GetStale(key string) ([]byte, error) {
+	return c.GetStaleTo(nil, key)
+}
+
+// GetStaleTo gets entry bytes to dst considering grace period.
+//
+// Expired entry within grace period (see Config.StaleInterval) returns together with ErrStale error.
+func (c *Cache) GetStaleTo(dst []byte, key string) ([]byte, error) {
+	e, err := c.getStaleEntry(dst, key)
+	return e.Body, err
+}
+
+// Get entry to dst considering grace period.
+func (c *Cache) getStaleEntry(dst []byte, key string) (Entry, error) {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return Entry{Body: dst}, err
+	}
+	h := c.config.Hasher.Sum64(key)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.getEntry(dst, h, false, true)
+}
+
+// GetOrRevalidate gets entry bytes by key or loads it using load function.
+//
+// See GetToOrRevalidate.
+func (c *Cache) GetOrRevalidate(key string, load Loader) ([]byte, error) {
+	return c.GetToOrRevalidate(nil, key, load)
+}
+
+// GetToOrRevalidate gets entry bytes to dst or loads it using load function.
+//
+// Stale entry (expired, but within grace period) returns immediately together with ErrStale error. At the same time
+// load function calls in background to replace stale entry with fresh one. Loaded data drops if the stale entry was
+// deleted or changed during the load. Missing entry loads synchronously like GetToOrLoad does.
+func (c *Cache) GetToOrRevalidate(dst []byte, key string, load Loader) ([]byte, error) {
+	if load == nil {
+		return dst, ErrNoLoader
+	}
+	off := len(dst)
+	e, err := c.getStaleEntry(dst, key)
+	if dst = e.Body; err == nil {
+		return dst, err
+	}
+	if err == ErrStale {
+		c.revalidate(key, e.Version, load)
+		return dst, err
+	}
+	return c.GetToOrLoad(dst[:off], key, load)
+}
+
+// Load fresh entry in background and replace stale one with ver version.
+//
+// Concurrent loads of the same key coalesce with each other and with GetOrLoad calls.
+func (c *Cache) revalidate(key string, ver uint64, load Loader) {
+	h := c.config.Hasher.Sum64(key)
+	lg := &c.lg
+	lg.mux.Lock()
+	if _, ok := lg.calls[h]; ok {
+		// Entry is already loading.
+		lg.mux.Unlock()
+		return
+	}
+	call := &loadCall{}
+	call.wg.Add(1)
+	lg.calls[h] = call
+	lg.mux.Unlock()
+
+	// Key may point to reusable memory, so copy it.
+	key = string(append(make([]byte, 0, len(key)), key...))
+	go func() {
+		defer func() {
+			lg.mux.Lock()
+			delete(lg.calls, h)
+			lg.mux.Unlock()
+			call.wg.Done()
+		}()
+		if call.body, call.err = load(); call.err != nil {
+			if logEnabled(c.l(), LevelWarn) {
+				c.l().Log(LevelWarn, "stale entry revalidation failed",
+					Field{"op", "revalidate"}, Field{"key", key}, Field{"error", call.err})
+			}
+			return
+		}
+		bkt := c.buckets[h%uint64(c.config.Buckets)]
+		err := bkt.replace(key, h, call.body, ver)
+		// Stale entry was deleted or changed during the load, so loaded data is outdated.
+		if err == ErrEntryVersion {
+			return
+		}
+		if err != nil && logEnabled(c.l(), LevelWarn) {
+			c.l().Log(LevelWarn, "stale entry replace failed",
+				Field{"op", "revalidate"}, Field{"key", key}, Field{"error", err})
+		}
+	}()
+}
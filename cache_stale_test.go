@@ -0,0 +1,130 @@
+package cbytecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestStale(t *testing.T) {
+	newCache := func(t *testing.T) (*Cache, *Config) {
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf.Clock = clock.NewClock()
+		conf.StaleInterval = time.Minute
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = cache.Set("foobar", getEntryBody(0)); err != nil {
+			t.Fatal(err)
+		}
+		return cache, conf
+	}
+	t.Run("get", func(t *testing.T) {
+		cache, conf := newCache(t)
+		// Expire entry and trigger eviction.
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+		if _, err := cache.Get("foobar"); err != ErrNotFound {
+			t.Errorf("need not found error, got %v", err)
+		}
+		body, err := cache.GetStale("foobar")
+		if err != ErrStale {
+			t.Errorf("need stale error, got %v", err)
+		}
+		assertBytes(t, getEntryBody(0), body)
+		// Leave grace period.
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+		if _, err = cache.GetStale("foobar"); err != ErrNotFound {
+			t.Errorf("need not found error, got %v", err)
+		}
+		_ = cache.Close()
+	})
+	t.Run("revalidate", func(t *testing.T) {
+		cache, conf := newCache(t)
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+		done := make(chan struct{})
+		load := func() ([]byte, error) {
+			defer close(done)
+			return getEntryBody(1), nil
+		}
+		body, err := cache.GetOrRevalidate("foobar", load)
+		if err != ErrStale {
+			t.Errorf("need stale error, got %v", err)
+		}
+		assertBytes(t, getEntryBody(0), body)
+		<-done
+		time.Sleep(time.Millisecond)
+		if body, err = cache.Get("foobar"); err != nil {
+			t.Fatal(err)
+		}
+		assertBytes(t, getEntryBody(1), body)
+		_ = cache.Close()
+	})
+	t.Run("revalidate deleted", func(t *testing.T) {
+		cache, conf := newCache(t)
+		defer func() { _ = cache.Close() }()
+		conf.Clock.Jump(time.Minute + time.Second)
+		release, done := make(chan struct{}), make(chan struct{})
+		load := func() ([]byte, error) {
+			defer close(done)
+			<-release
+			return getEntryBody(1), nil
+		}
+		if _, err := cache.GetOrRevalidate("foobar", load); err != ErrStale {
+			t.Errorf("need stale error, got %v", err)
+		}
+		// Entry deleted during the load must not be restored.
+		_ = cache.Delete("foobar")
+		close(release)
+		<-done
+		time.Sleep(time.Millisecond)
+		if _, err := cache.GetStale("foobar"); err != ErrNotFound {
+			t.Errorf("need not found error, got %v", err)
+		}
+	})
+	t.Run("overwrite expired", func(t *testing.T) {
+		cache, conf := newCache(t)
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+		if err := cache.Set("foobar", getEntryBody(2)); err != nil {
+			t.Fatal(err)
+		}
+		body, err := cache.Get("foobar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBytes(t, getEntryBody(2), body)
+		_ = cache.Close()
+	})
+	t.Run("replace no space", func(t *testing.T) {
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 2*Kilobyte)
+		conf.Buckets = 1
+		conf.ArenaCapacity = Kilobyte
+		conf.Clock = clock.NewClock()
+		conf.StaleInterval = time.Minute
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache.Close() }()
+		if err = cache.Set("foobar", []byte("stale")); err != nil {
+			t.Fatal(err)
+		}
+		conf.Clock.Jump(time.Minute + time.Second)
+		h := conf.Hasher.Sum64("foobar")
+		e, _ := cache.buckets[0].getEntry(nil, h, false, true)
+		if err = cache.buckets[0].replace("foobar", h, make([]byte, 4*Kilobyte), e.Version); err != ErrNoSpace {
+			t.Fatalf("need no space error, got %v", err)
+		}
+		body, err := cache.GetStale("foobar")
+		if err != ErrStale {
+			t.Errorf("need stale error, got %v", err)
+		}
+		assertBytes(t, []byte("stale"), body)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/koykov/byteconv"
+	"github.com/koykov/clock"
 	"github.com/koykov/hash/fnv"
 )
 
@@ -103,3 +104,26 @@ func TestExtract(t *testing.T) {
 		t.Errorf("error mismatch: need '%s', got '%s'", ErrNotFound.Error(), err.Error())
 	}
 }
+
+func TestGetEntry(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := getEntryBody(0)
+	if err = cache.Set("foobar", body); err != nil {
+		t.Fatal(err)
+	}
+	e, err := cache.GetEntryTo(nil, "foobar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertString(t, "foobar", e.Key)
+	assertBytes(t, body, e.Body)
+	if expect := uint32(conf.Clock.Now().Add(time.Minute).Unix()); e.Expire != expect {
+		t.Errorf("expire mismatch: need %d, got %d", expect, e.Expire)
+	}
+	_ = cache.Close()
+}
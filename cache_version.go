@@ -0,0 +1,48 @@
+package cbytecache
+
+// GetWithVersion gets entry bytes by key together with entry version.
+//
+// Version uses as CAS token in SetIfVersion.
+func (c *Cache) GetWithVersion(key string) ([]byte, uint64, error) {
+	return c.GetToWithVersion(nil, key)
+}
+
+// GetToWithVersion gets entry bytes to dst together with entry version.
+func (c *Cache) GetToWithVersion(dst []byte, key string) ([]byte, uint64, error) {
+	e, err := c.GetEntryTo(dst, key)
+	return e.Body, e.Version, err
+}
+
+// SetIfVersion sets entry bytes to the cache only if current entry version matches version (compare-and-swap).
+//
+// Use GetWithVersion to get current entry version. Zero version means that entry must not exist. Possible errors are:
+// ErrEntryVersion (entry changed since version read), ErrNotFound (entry deleted or expired) and ErrEntryExists
+// (zero version, but entry exists). Written entry gets new version.
+func (c *Cache) SetIfVersion(key string, data []byte, version uint64) error {
+	return c.SetEntryIfVersion(Entry{Key: key, Body: data}, version)
+}
+
+// SetEntryIfVersion sets entry with its absolute expire timestamp only if current entry version matches version.
+//
+// Zero expire means default expiration (see Config.ExpireInterval). See SetIfVersion for version details.
+func (c *Cache) SetEntryIfVersion(e Entry, version uint64) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if err := c.checkEntry(e.Key, uint32(len(e.Body))); err != nil {
+		return err
+	}
+	if len(e.Tags) > MaxTags {
+		return ErrTooManyTags
+	}
+	if e.Expire > 0 && e.Expire < c.now() {
+		return ErrEntryExpired
+	}
+	tn, err := c.tenantOf(e.Key)
+	if err != nil {
+		return err
+	}
+	h := nsHash(c.config.Hasher.Sum64(e.Key), e.Namespace)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.setIfVersion(&e, h, tn, version)
+}
@@ -0,0 +1,151 @@
+package cbytecache
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestVersion(t *testing.T) {
+	cache, err := New(DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	t.Run("cas", func(t *testing.T) {
+		if err := cache.SetIfVersion("foo", []byte("1"), 1); err != ErrNotFound {
+			t.Errorf("error mismatch: need ErrNotFound got %v", err)
+		}
+		if err := cache.SetIfVersion("foo", []byte("1"), 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.SetIfVersion("foo", []byte("1"), 0); err != ErrEntryExists {
+			t.Errorf("error mismatch: need ErrEntryExists got %v", err)
+		}
+		b, v, err := cache.GetWithVersion("foo")
+		if err != nil || string(b) != "1" || v == 0 {
+			t.Fatalf("get mismatch: %s %d %v", b, v, err)
+		}
+		if err = cache.SetIfVersion("foo", []byte("2"), v); err != nil {
+			t.Fatal(err)
+		}
+		if err = cache.SetIfVersion("foo", []byte("3"), v); err != ErrEntryVersion {
+			t.Errorf("error mismatch: need ErrEntryVersion got %v", err)
+		}
+		b, v1, _ := cache.GetWithVersion("foo")
+		if string(b) != "2" || v1 <= v {
+			t.Errorf("get mismatch: %s %d", b, v1)
+		}
+		// Deleted and written again entry gets new version.
+		_ = cache.Delete("foo")
+		_ = cache.Set("foo", []byte("4"))
+		if _, v2, _ := cache.GetWithVersion("foo"); v2 == v1 {
+			t.Error("version must change after rewrite")
+		}
+		if err = cache.SetIfVersion("foo", []byte("5"), v1); err != ErrEntryVersion {
+			t.Errorf("error mismatch: need ErrEntryVersion got %v", err)
+		}
+	})
+	t.Run("failed write", func(t *testing.T) {
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 64*Kilobyte)
+		conf.Buckets = 1
+		conf.ArenaCapacity = 4 * Kilobyte
+		cache1, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache1.Close() }()
+		_ = cache1.Set("foo", []byte("1"))
+		_, v, _ := cache1.GetWithVersion("foo")
+		// Entry doesn't fit to the bucket, so current entry must keep.
+		if err = cache1.SetIfVersion("foo", bytes.Repeat([]byte("x"), 128*1024), v); err == nil {
+			t.Fatal("oversize write must fail")
+		}
+		if b, v1, err := cache1.GetWithVersion("foo"); err != nil || string(b) != "1" || v1 != v {
+			t.Errorf("entry must keep after failed write: %s %d %v", b, v1, err)
+		}
+	})
+	t.Run("long expire", func(t *testing.T) {
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf.Buckets = 1
+		conf.ArenaCapacity = Kilobyte
+		conf.Clock = clock.NewClock()
+		cache1, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache1.Close() }()
+		_ = cache1.Set("long", []byte("1"))
+		_, v, _ := cache1.GetWithVersion("long")
+		now := uint32(conf.Clock.Now().Unix())
+		if err = cache1.SetEntryIfVersion(Entry{Key: "long", Body: []byte("2"), Expire: now + 3600}, v); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			_ = cache1.Set("key"+strconv.Itoa(i), getEntryBody(i))
+		}
+		// Entry with long expire survives eviction of entries written after it.
+		conf.Clock.Jump(time.Minute * 2)
+		for i := 0; i < 3; i++ {
+			_ = cache1.Set("new"+strconv.Itoa(i), getEntryBody(i))
+			_ = cache1.Evict()
+		}
+		if b, err := cache1.Get("long"); err != nil || string(b) != "2" {
+			t.Errorf("long entry mismatch: %s %v", b, err)
+		}
+		if _, err = cache1.Get("key0"); err != ErrNotFound {
+			t.Errorf("error mismatch: need ErrNotFound got %v", err)
+		}
+	})
+	t.Run("restart", func(t *testing.T) {
+		_ = cache.Set("bar", []byte("1"))
+		_, v, _ := cache.GetWithVersion("bar")
+		cache1, err := New(DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache1.Close() }()
+		// Versions of new cache instance (e.g. after restart and dump load) must not repeat versions issued before.
+		_ = cache1.Set("bar", []byte("1"))
+		if _, v1, _ := cache1.GetWithVersion("bar"); v1 <= v {
+			t.Errorf("version must grow after restart: %d <= %d", v1, v)
+		}
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		const workers, incs = 8, 100
+		_ = cache.Set("counter", []byte("0"))
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var buf []byte
+				for j := 0; j < incs; {
+					b, v, err := cache.GetToWithVersion(buf[:0], "counter")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					buf = b
+					n, _ := strconv.Atoi(string(b))
+					if err = cache.SetIfVersion("counter", []byte(strconv.Itoa(n+1)), v); err == nil {
+						j++
+					} else if err != ErrEntryVersion {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if b, _ := cache.Get("counter"); string(b) != strconv.Itoa(workers*incs) {
+			t.Errorf("counter mismatch: need %d got %s", workers*incs, b)
+		}
+	})
+}
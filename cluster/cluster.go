@@ -0,0 +1,225 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash"
+)
+
+const defaultVNodes = 128
+
+var (
+	ErrNoNodes      = errors.New("no nodes in cluster")
+	ErrNodeExists   = errors.New("node already exists")
+	ErrNodeNotFound = errors.New("node not found")
+	ErrNoNode       = errors.New("no node provided")
+	ErrNoName       = errors.New("empty node name")
+	ErrDumpSession  = errors.New("dump session not found")
+)
+
+// Node is the interface of single cluster node.
+//
+// *cbytecache.Cache implements it, use Client for remote nodes.
+type Node interface {
+	Set(key string, data []byte) error
+	GetTo(dst []byte, key string) ([]byte, error)
+	Delete(key string) error
+	SetEntry(e cbytecache.Entry) error
+	DumpTo(w cbytecache.DumpWriter) error
+}
+
+// Cluster distributes keys among nodes using consistent hashing ring.
+//
+// The ring uses the same hasher as nodes use for bucket selection.
+type Cluster struct {
+	mux   sync.RWMutex
+	nodes map[string]Node
+	ring  *ring
+}
+
+// New makes new empty cluster with given hasher and count of virtual nodes per node.
+//
+// If vnodes omit defaultVNodes (128) will use instead.
+func New(hasher hash.Hasher, vnodes int) (*Cluster, error) {
+	if hasher == nil {
+		return nil, cbytecache.ErrBadHasher
+	}
+	if vnodes <= 0 {
+		vnodes = defaultVNodes
+	}
+	c := Cluster{
+		nodes: make(map[string]Node),
+		ring:  &ring{hasher: hasher, vnodes: vnodes},
+	}
+	return &c, nil
+}
+
+// AddNode adds node to the cluster and moves to the new node all keys it owns now.
+//
+// All cluster operations are blocked during the handoff.
+func (c *Cluster) AddNode(name string, node Node) error {
+	if len(name) == 0 {
+		return ErrNoName
+	}
+	if node == nil {
+		return ErrNoNode
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if _, ok := c.nodes[name]; ok {
+		return ErrNodeExists
+	}
+	r := c.ring.clone()
+	r.add(name)
+	c.nodes[name] = node
+	var err error
+	for name1, node1 := range c.nodes {
+		if name1 == name {
+			continue
+		}
+		if err1 := c.handoff(r, name1, node1); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	c.ring = r
+	return err
+}
+
+// RemoveNode removes node from the cluster and moves all its keys to the rest nodes.
+//
+// All cluster operations are blocked during the handoff.
+func (c *Cluster) RemoveNode(name string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	node, ok := c.nodes[name]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	r := c.ring.clone()
+	r.remove(name)
+	var err error
+	if len(r.points) > 0 {
+		err = c.handoff(r, name, node)
+	}
+	delete(c.nodes, name)
+	c.ring = r
+	return err
+}
+
+// Nodes returns names of all cluster nodes.
+func (c *Cluster) Nodes() []string {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	names := make([]string, 0, len(c.nodes))
+	for name := range c.nodes {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Locate returns name of the node owns the key.
+func (c *Cluster) Locate(key string) string {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.ring.locate(c.ring.hasher.Sum64(key))
+}
+
+// Set sets entry bytes to the node owns the key.
+func (c *Cluster) Set(key string, data []byte) error {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	node, err := c.node(key)
+	if err != nil {
+		return err
+	}
+	return node.Set(key, data)
+}
+
+// Get returns entry bytes from the node owns the key.
+func (c *Cluster) Get(key string) ([]byte, error) {
+	return c.GetTo(nil, key)
+}
+
+// GetTo appends entry bytes from the node owns the key to dst.
+func (c *Cluster) GetTo(dst []byte, key string) ([]byte, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	node, err := c.node(key)
+	if err != nil {
+		return dst, err
+	}
+	return node.GetTo(dst, key)
+}
+
+// Delete deletes entry from the node owns the key.
+func (c *Cluster) Delete(key string) error {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	node, err := c.node(key)
+	if err != nil {
+		return err
+	}
+	return node.Delete(key)
+}
+
+// Get node owns the key.
+func (c *Cluster) node(key string) (Node, error) {
+	name := c.ring.locate(c.ring.hasher.Sum64(key))
+	if len(name) == 0 {
+		return nil, ErrNoNodes
+	}
+	return c.nodes[name], nil
+}
+
+// Move entries of the node that belong to another nodes according ring r.
+//
+// Entries write to new owners during the node dump, so entries bodies don't buffer. Moved keys delete from the node
+// after the dump, since the dump locks node buckets.
+func (c *Cluster) handoff(r *ring, name string, node Node) error {
+	w := handoffWriter{c: c, ring: r, name: name}
+	if err := node.DumpTo(&w); err != nil {
+		return err
+	}
+	for _, key := range w.keys {
+		_ = node.Delete(key)
+	}
+	return w.err
+}
+
+// DumpWriter implementation that writes entries owned by another nodes to their owners.
+type handoffWriter struct {
+	c    *Cluster
+	ring *ring
+	name string
+
+	mux sync.Mutex
+	// Keys of moved entries.
+	keys []string
+	// First write error.
+	err error
+}
+
+func (w *handoffWriter) Write(e cbytecache.Entry) (int, error) {
+	owner := w.ring.locate(w.ring.hasher.Sum64(e.Key))
+	if owner == w.name {
+		return 0, nil
+	}
+	err := w.c.nodes[owner].SetEntry(e)
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if err != nil && err != cbytecache.ErrEntryExpired {
+		if w.err == nil {
+			w.err = err
+		}
+		return 0, nil
+	}
+	// Key may point to reusable memory, so copy it.
+	w.keys = append(w.keys, string(append(make([]byte, 0, len(e.Key)), e.Key...)))
+	return e.Size(), nil
+}
+
+func (w *handoffWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+func newCache(t *testing.T) *cbytecache.Cache {
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
+
+func assertKeys(t *testing.T, c *Cluster, n int) {
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%d", i)
+		body, err := c.Get(key)
+		if err != nil {
+			t.Fatalf("key %s: %s", key, err)
+		}
+		if string(body) != fmt.Sprintf("body%d", i) {
+			t.Errorf("key %s body mismatch: got %s", key, body)
+		}
+	}
+}
+
+func TestCluster(t *testing.T) {
+	const n = 1000
+	c, err := New(&fnv.Hasher{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Set("foo", []byte("bar")); err != ErrNoNodes {
+		t.Errorf("empty cluster must fail, got %v", err)
+	}
+	nodes := make(map[string]*cbytecache.Cache)
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("node%d", i)
+		nodes[name] = newCache(t)
+		if err = c.AddNode(name, nodes[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if err = c.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("body%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertKeys(t, c, n)
+
+	nodes["node3"] = newCache(t)
+	t.Run("add", func(t *testing.T) {
+		if err = c.AddNode("node3", nodes["node3"]); err != nil {
+			t.Fatal(err)
+		}
+		assertKeys(t, c, n)
+		var moved int
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("key%d", i)
+			if c.Locate(key) == "node3" {
+				moved++
+				if _, err = nodes["node3"].Get(key); err != nil {
+					t.Errorf("key %s must be moved to node3", key)
+				}
+			}
+		}
+		if moved == 0 {
+			t.Error("no keys moved to new node")
+		}
+	})
+	t.Run("remove", func(t *testing.T) {
+		if err = c.RemoveNode("node0"); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Nodes()) != 3 {
+			t.Errorf("nodes count mismatch: need 3 got %d", len(c.Nodes()))
+		}
+		assertKeys(t, c, n)
+	})
+	t.Run("delete", func(t *testing.T) {
+		_ = c.Delete("key0")
+		if _, err = c.Get("key0"); err != cbytecache.ErrNotFound {
+			t.Errorf("key0 must be deleted, got %v", err)
+		}
+	})
+}
+
+func TestRemoteNode(t *testing.T) {
+	// Enough keys to dump remote node in several pages.
+	const n = 2000
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	srv := rpc.NewServer()
+	remote := newCache(t)
+	if err = NewServer(remote).Register(srv); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Accept(ln)
+	cli, err := Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cli.Close() }()
+
+	c, _ := New(&fnv.Hasher{}, 0)
+	_ = c.AddNode("local", newCache(t))
+	if err = c.AddNode("remote", cli); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		_ = c.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("body%d", i)))
+	}
+	assertKeys(t, c, n)
+	if _, err = cli.GetTo(nil, "missing"); err != cbytecache.ErrNotFound {
+		t.Errorf("remote error mismatch: need ErrNotFound got %v", err)
+	}
+	var cnt countWriter
+	if err = cli.DumpTo(&cnt); err != nil {
+		t.Fatal(err)
+	}
+	if cnt.n <= defaultDumpPage || cnt.n != remote.Stats().Entries {
+		t.Errorf("remote dump mismatch: got %d entries of %d", cnt.n, remote.Stats().Entries)
+	}
+	if err = c.RemoveNode("remote"); err != nil {
+		t.Fatal(err)
+	}
+	assertKeys(t, c, n)
+}
+
+type countWriter struct {
+	mux sync.Mutex
+	n   uint64
+}
+
+func (w *countWriter) Write(e cbytecache.Entry) (int, error) {
+	w.mux.Lock()
+	w.n++
+	w.mux.Unlock()
+	return e.Size(), nil
+}
+
+func (w *countWriter) Flush() error { return nil }
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/koykov/hash"
+)
+
+// Ring point of virtual node.
+type point struct {
+	hash uint64
+	node string
+}
+
+// Consistent hashing ring.
+type ring struct {
+	hasher hash.Hasher
+	vnodes int
+	points []point
+	buf    []byte
+}
+
+// Add virtual nodes of the node to the ring.
+func (r *ring) add(node string) {
+	for i := 0; i < r.vnodes; i++ {
+		r.buf = append(r.buf[:0], node...)
+		r.buf = append(r.buf, '#')
+		r.buf = strconv.AppendInt(r.buf, int64(i), 10)
+		r.points = append(r.points, point{hash: r.hasher.Sum64(string(r.buf)), node: node})
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].node < r.points[j].node
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+}
+
+// Remove all virtual nodes of the node from the ring.
+func (r *ring) remove(node string) {
+	points := r.points[:0]
+	for _, p := range r.points {
+		if p.node != node {
+			points = append(points, p)
+		}
+	}
+	r.points = points
+}
+
+// Get node owns the key hash.
+func (r *ring) locate(h uint64) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node
+}
+
+// Make a copy of the ring.
+func (r *ring) clone() *ring {
+	cpy := ring{hasher: r.hasher, vnodes: r.vnodes}
+	cpy.points = append(cpy.points, r.points...)
+	return &cpy
+}
@@ -0,0 +1,314 @@
+package cluster
+
+import (
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/koykov/cbytecache"
+)
+
+// Name of the RPC service.
+const rpcService = "CacheNode"
+
+const (
+	// Default and max count of entries in dump page.
+	defaultDumpPage = 256
+	maxDumpPage     = 4096
+	// Dump session aborts if client doesn't request next page during that period.
+	dumpIdleTimeout = time.Minute
+)
+
+// Server exposes node to remote clusters via net/rpc.
+//
+// Usage:
+//
+//	srv := rpc.NewServer()
+//	_ = cluster.NewServer(cache).Register(srv)
+//	go srv.Accept(ln)
+type Server struct {
+	node Node
+
+	mux   sync.Mutex
+	seq   uint64
+	dumps map[uint64]*dumpSession
+}
+
+// Args represents key/body RPC arguments.
+type Args struct {
+	Key  string
+	Body []byte
+}
+
+// DumpArgs represents arguments of dump page request.
+type DumpArgs struct {
+	// Dump session ID, zero starts new session.
+	ID uint64
+	// Max count of entries in the page.
+	Limit int
+}
+
+// DumpPage represents single page of node dump.
+type DumpPage struct {
+	ID      uint64
+	Entries []cbytecache.Entry
+	// Done indicates the last page.
+	Done bool
+}
+
+// NewServer makes new RPC server over given node.
+func NewServer(node Node) *Server {
+	return &Server{node: node, dumps: make(map[uint64]*dumpSession)}
+}
+
+// Register registers server in RPC server srv.
+func (s *Server) Register(srv *rpc.Server) error {
+	return srv.RegisterName(rpcService, s)
+}
+
+func (s *Server) Set(args Args, _ *struct{}) error {
+	return s.node.Set(args.Key, args.Body)
+}
+
+func (s *Server) Get(key string, reply *[]byte) (err error) {
+	*reply, err = s.node.GetTo((*reply)[:0], key)
+	return
+}
+
+func (s *Server) Delete(key string, _ *struct{}) error {
+	return s.node.Delete(key)
+}
+
+func (s *Server) SetEntry(e cbytecache.Entry, _ *struct{}) error {
+	return s.node.SetEntry(e)
+}
+
+// DumpPage returns next page of node dump. Request with zero session ID starts new dump session.
+//
+// Node dumps to the session in background and blocks until client takes the entries, so the whole dump never
+// buffers in memory. The last page has Done flag, after that session closes.
+func (s *Server) DumpPage(args DumpArgs, reply *DumpPage) error {
+	id, d := args.ID, s.session(args.ID)
+	if d == nil {
+		if id != 0 {
+			return ErrDumpSession
+		}
+		id, d = s.startDump()
+	}
+	limit := args.Limit
+	if limit <= 0 || limit > maxDumpPage {
+		limit = defaultDumpPage
+	}
+	reply.ID = id
+	for len(reply.Entries) < limit {
+		var (
+			e  cbytecache.Entry
+			ok bool
+		)
+		if len(reply.Entries) == 0 {
+			// Wait for the first entry of the page.
+			select {
+			case e, ok = <-d.ch:
+			case <-d.abort:
+				return ErrDumpSession
+			}
+		} else {
+			select {
+			case e, ok = <-d.ch:
+			default:
+				return nil
+			}
+		}
+		if !ok {
+			reply.Done = true
+			s.stopDump(id)
+			return d.err
+		}
+		reply.Entries = append(reply.Entries, e)
+	}
+	return nil
+}
+
+// DumpClose aborts dump session.
+func (s *Server) DumpClose(id uint64, _ *struct{}) error {
+	s.stopDump(id)
+	return nil
+}
+
+// Get dump session by ID.
+func (s *Server) session(id uint64) *dumpSession {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.dumps[id]
+}
+
+// Start new dump session.
+func (s *Server) startDump() (uint64, *dumpSession) {
+	d := &dumpSession{
+		srv:   s,
+		ch:    make(chan cbytecache.Entry, defaultDumpPage),
+		abort: make(chan struct{}),
+	}
+	s.mux.Lock()
+	s.seq++
+	id := s.seq
+	d.id = id
+	s.dumps[id] = d
+	s.mux.Unlock()
+
+	go func() {
+		d.err = s.node.DumpTo(d)
+		close(d.ch)
+		// Client may never take the rest of entries.
+		t := time.NewTimer(dumpIdleTimeout)
+		defer t.Stop()
+		select {
+		case <-d.abort:
+		case <-t.C:
+			s.stopDump(id)
+		}
+	}()
+	return id, d
+}
+
+// Close and forget dump session.
+func (s *Server) stopDump(id uint64) {
+	s.mux.Lock()
+	d, ok := s.dumps[id]
+	delete(s.dumps, id)
+	s.mux.Unlock()
+	if ok {
+		close(d.abort)
+	}
+}
+
+// Client is a Node implementation over net/rpc connection to the remote Server.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects to the remote node.
+func Dial(network, addr string) (*Client, error) {
+	c, err := rpc.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient makes new node client over RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c: c}
+}
+
+func (c *Client) Set(key string, data []byte) error {
+	return c.call("Set", Args{Key: key, Body: data}, &struct{}{})
+}
+
+func (c *Client) GetTo(dst []byte, key string) ([]byte, error) {
+	var body []byte
+	if err := c.call("Get", key, &body); err != nil {
+		return dst, err
+	}
+	return append(dst, body...), nil
+}
+
+func (c *Client) Delete(key string) error {
+	return c.call("Delete", key, &struct{}{})
+}
+
+func (c *Client) SetEntry(e cbytecache.Entry) error {
+	return c.call("SetEntry", e, &struct{}{})
+}
+
+// DumpTo reads remote node dump page by page and writes entries to w.
+func (c *Client) DumpTo(w cbytecache.DumpWriter) error {
+	args := DumpArgs{Limit: defaultDumpPage}
+	for {
+		// Gob doesn't decode zero fields, so reply must be clean.
+		var page DumpPage
+		if err := c.call("DumpPage", args, &page); err != nil {
+			return err
+		}
+		args.ID = page.ID
+		for _, e := range page.Entries {
+			if _, err := w.Write(e); err != nil {
+				if !page.Done {
+					_ = c.call("DumpClose", page.ID, &struct{}{})
+				}
+				return err
+			}
+		}
+		if page.Done {
+			break
+		}
+	}
+	return w.Flush()
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.c.Close()
+}
+
+// Call remote method and restore known cache errors.
+func (c *Client) call(method string, args, reply any) error {
+	err := c.c.Call(rpcService+"."+method, args, reply)
+	if se, ok := err.(rpc.ServerError); ok {
+		if known, ok := knownErrors[string(se)]; ok {
+			return known
+		}
+	}
+	return err
+}
+
+// Cache errors that may be returned by remote node.
+var knownErrors = func() map[string]error {
+	m := make(map[string]error)
+	for _, err := range []error{
+		cbytecache.ErrNotFound, cbytecache.ErrEntryExists, cbytecache.ErrEntryExpired, cbytecache.ErrEntryTooBig,
+		cbytecache.ErrEntryEmpty, cbytecache.ErrKeyTooBig, cbytecache.ErrNoSpace, cbytecache.ErrCacheClosed,
+		cbytecache.ErrBucketService, cbytecache.ErrEntryCollision, cbytecache.ErrNoDumpWriter, ErrDumpSession,
+	} {
+		m[err.Error()] = err
+	}
+	return m
+}()
+
+// Dump session is a DumpWriter implementation that passes entries to the client.
+type dumpSession struct {
+	srv *Server
+	id  uint64
+	ch  chan cbytecache.Entry
+	// Closes on session close.
+	abort chan struct{}
+	// Dump result, valid after ch close.
+	err error
+}
+
+func (d *dumpSession) Write(e cbytecache.Entry) (int, error) {
+	e = e.Copy()
+	select {
+	case d.ch <- e:
+		return e.Size(), nil
+	case <-d.abort:
+		return 0, ErrDumpSession
+	default:
+	}
+	// Node dump holds buckets locks, so gone client must not block it forever.
+	t := time.NewTimer(dumpIdleTimeout)
+	defer t.Stop()
+	select {
+	case d.ch <- e:
+		return e.Size(), nil
+	case <-d.abort:
+		return 0, ErrDumpSession
+	case <-t.C:
+		d.srv.stopDump(d.id)
+		return 0, ErrDumpSession
+	}
+}
+
+func (d *dumpSession) Flush() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+// Command cbytecache-memcache serves cbytecache instance over memcached text protocol.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/cbytecache/server/memcache"
+	"github.com/koykov/hash/fnv"
+)
+
+var (
+	fAddr     = flag.String("addr", ":11211", "TCP address to listen")
+	fExpire   = flag.Duration("expire", time.Hour, "default entry expiration interval")
+	fVacuum   = flag.Duration("vacuum", 0, "vacuum interval, zero disables vacuum")
+	fCapacity = flag.Uint64("capacity", 0, "max cache capacity in megabytes, zero means unlimited")
+	fBuckets  = flag.Uint("buckets", 16, "cache buckets count")
+	fFlags    = flag.Bool("flags", false, "store client flags")
+)
+
+func main() {
+	flag.Parse()
+
+	conf := cbytecache.DefaultConfig(*fExpire, &fnv.Hasher{}, cbytecache.MemorySize(*fCapacity)*cbytecache.Megabyte)
+	conf.Buckets = *fBuckets
+	conf.VacuumInterval = *fVacuum
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv, err := memcache.NewServer(cache)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv.WithFlags(*fFlags)
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		_ = srv.Close()
+	}()
+
+	log.Printf("listen %s", *fAddr)
+	if err = srv.ListenAndServe(*fAddr); err != nil && err != memcache.ErrClosed {
+		log.Fatal(err)
+	}
+	_ = cache.Close()
+}
@@ -25,6 +25,11 @@ type Config struct {
 	// Mandatory param.
 	ExpireInterval time.Duration
 
+	// StaleInterval represents grace period of expired entries. Eviction keeps expired entries during that period, so
+	// they are available using GetStale/GetOrRevalidate methods.
+	// If this param omit expired entries may be evicted at any time.
+	StaleInterval time.Duration
+
 	// EvictInterval represents period between eviction operations.
 	// If this param omit ExpireInterval will use instead.
 	EvictInterval time.Duration
@@ -54,15 +59,73 @@ type Config struct {
 	// If this param omit defaultReleaseWorkers (16) will use instead.
 	ReleaseWorkers uint
 
+	// LoadNegativeExpire represents lifetime of negative GetOrLoad results (loader returns error wraps ErrNotFound).
+	// Repeated loads of missing entries during that period return ErrNotFound without calling the loader.
+	// Negative results store as known missing entries, see Cache.SetMissing.
+	// If this param omit negative results will not cache.
+	LoadNegativeExpire time.Duration
+	// MissingLimit limits count of known missing entries per bucket (see Cache.SetMissing). If limit reached, 1/8 of
+	// the limit frees at once: expired marks remove first, then marks closest to expiration among few random marks.
+	// If this param omit defaultMissingLimit (4096) will use instead.
+	MissingLimit uint
+
 	// CollisionCheck enables collision checks.
 	CollisionCheck bool
+	// OptimisticReads enables optimistic read and write mode. In that mode readers hold bucket lock only to lookup the
+	// entry and copy entry data after unlock, writers hold the lock only to reserve arenas space and to publish the
+	// entry. Readers check arenas epoch after copy and repeat read under the lock if service operation happened in the
+	// meantime. Costs few extra atomic operations per call, thus makes sense on many-core machines.
+	OptimisticReads bool
 
 	// Clock implementation.
 	// If this param omit nativeClock{} will use instead.
 	Clock Clock
 
 	// ExpireListener triggers on every expired item.
+	// By default, listener calls synchronously during eviction, so slow listener stalls eviction.
 	ExpireListener Listener
+	// ExpireListenerAsync enables asynchronous delivery of expired entries to the listener through bounded buffer.
+	ExpireListenerAsync bool
+	// ExpireListenerBuffer limits count of expired entries waiting for delivery.
+	// If this param omit defaultExpireListenerBuffer (1024) will use instead.
+	ExpireListenerBuffer uint
+	// ExpireListenerWorkers limits count of workers that call the listener.
+	// If this param omit defaultExpireListenerWorkers (1) will use instead.
+	ExpireListenerWorkers uint
+	// ExpireListenerPolicy represents behavior when buffer is full: block (default), drop or spill.
+	ExpireListenerPolicy ListenPolicy
+	// ExpireListenerSpill receives entries that don't fit to the buffer in ListenSpill policy.
+	ExpireListenerSpill Listener
+
+	// Refresher triggers for entries near expiration to replace them with fresh data before expire.
+	// Check of entries performs together with eviction, so it happens every EvictInterval.
+	Refresher Refresher
+	// RefreshWindow represents period before entry expiration when entry must be refreshed.
+	// Mandatory param if Refresher provided, otherwise New returns ErrRefreshWindow.
+	RefreshWindow time.Duration
+	// RefreshWorkers limits workers count that calls Refresher.
+	// If this param omit defaultRefreshWorkers (4) will use instead.
+	RefreshWorkers uint
+	// RefreshQueueSize limits count of entries waiting for refresh. Entries that don't fit to the queue will skip
+	// until next eviction, so refresh storm doesn't block maintenance.
+	// If this param omit defaultRefreshQueueSize (1024) will use instead.
+	RefreshQueueSize uint
+
+	// EventListener triggers on every expired, deleted, extracted, reset or released entry.
+	EventListener EventListener
+
+	// MutationStream receives all cache changes: written, deleted and expired entries.
+	// Use Cache.Apply on another cache instance to replicate changes.
+	MutationStream MutationStream
+
+	// TenantFunc derives tenant name from entry key, see PrefixTenant. Tenants get separate memory accounting and
+	// optional quotas, see Cache.SetTenantQuota.
+	// If this param omit, entries belong to tenants only if tenant passed explicitly (see Cache.SetWithTenant).
+	TenantFunc TenantFunc
+	// TenantLimit limits count of tenants, so TenantFunc with too many distinct names can't bypass quotas. Writes on
+	// behalf of new tenants over the limit fail with ErrTenantLimit.
+	// If this param omit defaultTenantLimit (1024) will use instead. Max value is math.MaxUint16.
+	TenantLimit uint
 
 	// DumpWriter represents writer for dumps.
 	DumpWriter DumpWriter
@@ -83,9 +146,17 @@ type Config struct {
 	DumpReadAsync bool
 
 	// Metrics writer handler.
+	// If writer implements io.Closer it will close together with the cache.
 	MetricsWriter MetricsWriter
-	// Logger is a logging interface to display verbose messages.
+	// Logger is a printf logging interface to display verbose messages.
+	// Messages converts to plain text using NewPrintfLogger shim. Takes no effect if StructLogger provided.
 	Logger Logger
+	// LogLevel limits messages written to Logger.
+	// If this param omit LevelDebug will use, so all messages including per-bucket maintenance messages (evict,
+	// vacuum, dump, ...) will write. Set LevelInfo or above to skip them.
+	LogLevel Level
+	// StructLogger is a structured logging interface. Messages contains fields like bucket, op, count, duration, ...
+	StructLogger StructLogger
 }
 
 // Copy copies config instance to protect cache from changing params after start.
@@ -8,12 +8,14 @@ import (
 const (
 	MaxBucketSize = math.MaxUint32
 	MaxKeySize    = math.MaxUint16
+	MaxTags       = 64
 
 	MinExpireInterval = time.Second
 
 	defaultArenaCapacity = Kilobyte * 16
 
 	keySizeBytes = 2
+	nsSizeBytes  = 2
 
 	cacheStatusNil    = 0
 	cacheStatusActive = 1
@@ -29,4 +31,16 @@ const (
 	defaultVacuumWorkers    = 16
 	defaultDumpWriteWorkers = 16
 	defaultDumpReadWorkers  = 16
+	defaultRefreshWorkers   = 4
+	defaultRefreshQueueSize = 1024
+	defaultMissingLimit     = 4096
+	defaultTenantLimit      = 1024
+
+	// Count of random known missing entries to choose the evicted one.
+	missingEvictSamples = 5
+	// Missing limit divisor to get count of marks that free at once when limit reached.
+	missingEvictBatch = 8
+
+	defaultExpireListenerBuffer  = 1024
+	defaultExpireListenerWorkers = 1
 )
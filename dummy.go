@@ -13,11 +13,21 @@ func (DummyMetrics) Hit(_ string, _ time.Duration) {}
 func (DummyMetrics) Del(_ string)                  {}
 func (DummyMetrics) Evict(_ string, _ bool)        {}
 func (DummyMetrics) Miss(_ string)                 {}
+func (DummyMetrics) SetMissing(_ string)           {}
+func (DummyMetrics) HitMissing(_ string)           {}
 func (DummyMetrics) Expire(_ string)               {}
 func (DummyMetrics) Corrupt(_ string)              {}
 func (DummyMetrics) Collision(_ string)            {}
 func (DummyMetrics) NoSpace(_ string)              {}
 func (DummyMetrics) Dump(_ string)                 {}
 func (DummyMetrics) Load(_ string)                 {}
+func (DummyMetrics) ListenerError(_ string)        {}
+func (DummyMetrics) ListenerDrop(_ string)         {}
+
+func (DummyMetrics) NamespaceSet(_ string, _ time.Duration) {}
+func (DummyMetrics) NamespaceHit(_ string, _ time.Duration) {}
+func (DummyMetrics) NamespaceDel(_ string)                  {}
+func (DummyMetrics) NamespaceMiss(_ string)                 {}
+func (DummyMetrics) NamespaceNoSpace(_ string)              {}
 
 var dummyMetrics = DummyMetrics{}
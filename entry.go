@@ -16,6 +16,12 @@ type entry struct {
 	aid uint32
 	// Queue raw pointer.
 	qp uintptr
+	// Namespace ID, zero means default namespace.
+	ns uint16
+	// Tenant ID, zero means no tenant.
+	tn uint16
+	// Entry version, see bucket.nextVersionLF.
+	ver uint64
 }
 
 // Get starting arena contains entry data.
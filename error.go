@@ -14,17 +14,32 @@ var (
 	ErrBadHasher      = errors.New("you must provide hasher helper")
 	ErrBadBuckets     = errors.New("buckets count must be greater than zero")
 	ErrKeyTooBig      = fmt.Errorf("key overflows maximum %d", MaxKeySize)
+	ErrTooManyTags    = fmt.Errorf("tags count overflows maximum %d", MaxTags)
 	ErrNotFound       = errors.New("entry not found")
+	ErrStale          = errors.New("entry is stale")
+	ErrMissing        = errors.New("entry known as missing")
 	ErrEntryExists    = errors.New("entry already exists")
 	ErrEntryTooBig    = errors.New("entry too big")
 	ErrEntryEmpty     = errors.New("entry is empty")
 	ErrEntryCorrupt   = errors.New("entry corrupted")
 	ErrEntryCollision = errors.New("entry keys collision")
+	ErrEntryExpired   = errors.New("entry already expired")
+	ErrEntryVersion   = errors.New("entry version mismatch")
+	ErrBadMutation    = errors.New("unknown mutation type")
 	ErrExpireDur      = errors.New("expire interval is too short")
 	ErrVacuumDur      = errors.New("vacuum interval must be greater than expire interval")
+	ErrRefreshWindow  = errors.New("refresh window must be greater than zero")
 	ErrBucketService  = errors.New("cache bucket is under maintenance")
 	ErrBucketCorrupt  = errors.New("cache bucket is corrupted")
 	ErrNoSpace        = errors.New("no space available")
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+	ErrBadNamespace   = errors.New("namespace name is empty")
+	ErrNamespaceClash = errors.New("namespace ID is taken by other namespace")
+	ErrBadTenant      = errors.New("tenant name is empty")
+	ErrTenantLimit    = errors.New("tenants limit reached")
 	ErrNoEnqueuer     = errors.New("no enqueuer provided")
+	ErrNoDumpWriter   = errors.New("no dump writer provided")
 	ErrNoUnmarshaller = errors.New("no unmarshaller provided")
+	ErrNoLoader       = errors.New("no loader provided")
+	ErrLoadPanic      = errors.New("loader panicked")
 )
@@ -0,0 +1,59 @@
+package cbytecache
+
+// EventType represents type of entry event.
+type EventType uint8
+
+const (
+	// EventExpire triggers on expired entries during eviction.
+	EventExpire EventType = iota
+	// EventDelete triggers on explicit entry delete.
+	EventDelete
+	// EventExtract triggers on entry extract.
+	EventExtract
+	// EventReset triggers on every alive entry during cache reset.
+	EventReset
+	// EventRelease triggers on every alive entry during cache release.
+	EventRelease
+	// EventEvict triggers on alive entry deleted during eviction to fit namespace or tenant quota.
+	EventEvict
+
+	// Internal type of written entry, uses only for mutation stream.
+	eventSet EventType = 254
+	// Internal type to skip event.
+	eventNone EventType = 255
+)
+
+// Event represents entry event.
+type Event struct {
+	Type  EventType
+	Entry Entry
+}
+
+// EventListener is the interface that wraps the basic OnEvent method.
+//
+// Unlike Listener, it receives not only expired entries, but deleted, extracted, reset and released too.
+// Listener calls synchronously under bucket lock, so implementation must be fast. Event entry data is valid only
+// inside the call, use Entry.Copy to keep it.
+type EventListener interface {
+	OnEvent(event Event) error
+}
+
+// String returns event type name.
+func (t EventType) String() string {
+	switch t {
+	case EventExpire:
+		return "expire"
+	case EventDelete:
+		return "delete"
+	case EventExtract:
+		return "extract"
+	case EventReset:
+		return "reset"
+	case EventRelease:
+		return "release"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
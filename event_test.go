@@ -0,0 +1,87 @@
+package cbytecache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+type testEventListener struct {
+	mux sync.Mutex
+	c   map[EventType]int
+	e   []Event
+}
+
+func (l *testEventListener) OnEvent(event Event) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.c == nil {
+		l.c = make(map[EventType]int)
+	}
+	l.c[event.Type]++
+	l.e = append(l.e, Event{Type: event.Type, Entry: event.Entry.Copy()})
+	return nil
+}
+
+func (l *testEventListener) count(typ EventType) int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.c[typ]
+}
+
+func TestEventListener(t *testing.T) {
+	var l testEventListener
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	conf.EventListener = &l
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expire := uint32(conf.Clock.Now().Add(time.Minute).Unix())
+	for i := 0; i < 10; i++ {
+		if err = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = cache.Delete("key0")
+	_, _ = cache.Extract("key1")
+	if n := l.count(EventDelete); n != 1 {
+		t.Errorf("delete events mismatch: need 1 got %d", n)
+	}
+	if n := l.count(EventExtract); n != 1 {
+		t.Errorf("extract events mismatch: need 1 got %d", n)
+	}
+	ev := l.e[0]
+	assertString(t, "key0", ev.Entry.Key)
+	assertBytes(t, getEntryBody(0), ev.Entry.Body)
+	if ev.Entry.Expire != expire {
+		t.Errorf("expire mismatch: need %d got %d", expire, ev.Entry.Expire)
+	}
+
+	_ = cache.Reset()
+	if n := l.count(EventReset); n != 8 {
+		t.Errorf("reset events mismatch: need 8 got %d", n)
+	}
+
+	for i := 0; i < 5; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i))
+	}
+	conf.Clock.Jump(time.Minute + time.Second)
+	time.Sleep(time.Millisecond * 5)
+	if n := l.count(EventExpire); n != 5 {
+		t.Errorf("expire events mismatch: need 5 got %d", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i))
+	}
+	_ = cache.Close()
+	if n := l.count(EventRelease); n != 3 {
+		t.Errorf("release events mismatch: need 3 got %d", n)
+	}
+}
@@ -0,0 +1,22 @@
+package cbytecache
+
+// Interface describes basic cache operations.
+//
+// Cache implements it, as well as remote.Client does, so application may swap in-process and remote cache without
+// code changes.
+type Interface interface {
+	// Set sets entry bytes to the cache.
+	Set(key string, data []byte) error
+	// Get returns entry bytes.
+	Get(key string) ([]byte, error)
+	// GetTo appends entry bytes to dst.
+	GetTo(dst []byte, key string) ([]byte, error)
+	// Delete deletes entry.
+	Delete(key string) error
+	// Extract returns entry bytes and deletes the entry.
+	Extract(key string) ([]byte, error)
+	// Size returns cache size snapshot.
+	Size() CacheSize
+}
+
+var _ Interface = (*Cache)(nil)
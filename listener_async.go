@@ -0,0 +1,95 @@
+package cbytecache
+
+import "sync"
+
+// ListenPolicy represents behavior of asynchronous expire listener when its buffer is full.
+type ListenPolicy int
+
+const (
+	// ListenBlock waits for free space in the buffer. Eviction stalls until listener catches up.
+	ListenBlock ListenPolicy = iota
+	// ListenDrop drops the entry.
+	ListenDrop
+	// ListenSpill passes the entry to Config.ExpireListenerSpill synchronously.
+	ListenSpill
+)
+
+// Asynchronous wrapper over expire listener.
+type asyncListener struct {
+	config *Config
+	mw     ListenerMetricsWriter
+	queue  chan listenTask
+	wg     sync.WaitGroup
+
+	mux    sync.RWMutex
+	closed bool
+}
+
+// Single listener task.
+type listenTask struct {
+	bucket string
+	entry  Entry
+}
+
+// Make and start asynchronous listener.
+func newAsyncListener(conf *Config) *asyncListener {
+	l := asyncListener{
+		config: conf,
+		mw:     listenerMetricsOf(conf.MetricsWriter),
+		queue:  make(chan listenTask, conf.ExpireListenerBuffer),
+	}
+	for i := uint(0); i < conf.ExpireListenerWorkers; i++ {
+		l.wg.Add(1)
+		go l.work()
+	}
+	return &l
+}
+
+// Send entry to the listener according policy.
+func (l *asyncListener) send(bucket string, e Entry) {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	if l.closed {
+		l.mw.ListenerDrop(bucket)
+		return
+	}
+	t := listenTask{bucket: bucket, entry: e.Copy()}
+	if l.config.ExpireListenerPolicy == ListenBlock {
+		l.queue <- t
+		return
+	}
+	select {
+	case l.queue <- t:
+	default:
+		if l.config.ExpireListenerPolicy == ListenSpill && l.config.ExpireListenerSpill != nil {
+			if err := l.config.ExpireListenerSpill.Listen(t.entry); err != nil {
+				l.mw.ListenerError(bucket)
+			}
+			return
+		}
+		l.mw.ListenerDrop(bucket)
+	}
+}
+
+// Listener worker.
+func (l *asyncListener) work() {
+	defer l.wg.Done()
+	for t := range l.queue {
+		if err := l.config.ExpireListener.Listen(t.entry); err != nil {
+			l.mw.ListenerError(t.bucket)
+		}
+	}
+}
+
+// Stop workers and wait for buffered entries.
+func (l *asyncListener) stop() {
+	l.mux.Lock()
+	if l.closed {
+		l.mux.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.queue)
+	l.mux.Unlock()
+	l.wg.Wait()
+}
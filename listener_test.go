@@ -75,4 +75,106 @@ func TestListener(t *testing.T) {
 			t.Error(err.Error())
 		}
 	})
+	t.Run("async", func(t *testing.T) {
+		const count = 1000
+
+		var l countListener
+
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf.Clock = clock.NewClock()
+		conf.ExpireListener = &l
+		conf.ExpireListenerAsync = true
+		conf.ExpireListenerWorkers = 4
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < count; i++ {
+			if err = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+
+		// Close waits for buffered entries.
+		if err = cache.Close(); err != nil {
+			t.Error(err.Error())
+		}
+		k, n, b, c := l.stats()
+		if k != 0 || n != 0 || b != 0 || c != count {
+			t.Errorf("unexpected stats: %d, %d, %d, %d", k, n, b, c)
+		}
+	})
+	t.Run("async drop", func(t *testing.T) {
+		const count = 100
+
+		var (
+			l  countListener
+			mw listenerMetrics
+		)
+		block := make(chan struct{})
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf.Clock = clock.NewClock()
+		conf.ExpireListener = blockListener{l: &l, c: block}
+		conf.ExpireListenerAsync = true
+		conf.ExpireListenerBuffer = 10
+		conf.ExpireListenerPolicy = ListenDrop
+		conf.MetricsWriter = &mw
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < count; i++ {
+			if err = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+		close(block)
+		if err = cache.Close(); err != nil {
+			t.Error(err.Error())
+		}
+		_, _, _, c := l.stats()
+		if d := atomic.LoadUint32(&mw.drop); c+d != count || d == 0 {
+			t.Errorf("unexpected delivered/dropped counters: %d/%d", c, d)
+		}
+	})
+	t.Run("base metrics", func(t *testing.T) {
+		// Metrics writer without listener methods still works, listener errors just don't count.
+		conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf.Clock = clock.NewClock()
+		conf.ExpireListener = &countListener{}
+		conf.MetricsWriter = struct{ MetricsWriter }{DummyMetrics{}}
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = cache.Set("foobar", []byte("qwe"))
+		conf.Clock.Jump(time.Minute + time.Second)
+		time.Sleep(time.Millisecond * 5)
+		if err = cache.Close(); err != nil {
+			t.Error(err.Error())
+		}
+	})
+}
+
+type blockListener struct {
+	l *countListener
+	c chan struct{}
+}
+
+func (l blockListener) Listen(entry Entry) error {
+	<-l.c
+	return l.l.Listen(entry)
+}
+
+type listenerMetrics struct {
+	DummyMetrics
+	drop uint32
+}
+
+func (m *listenerMetrics) ListenerDrop(_ string) {
+	atomic.AddUint32(&m.drop, 1)
 }
@@ -1,8 +1,132 @@
 package cbytecache
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Logger is the interface that wraps the basic logging methods.
+//
+// Printf logger receives messages of all levels since LogLevel in config. Use StructLogger to get fields and levels
+// separately.
 type Logger interface {
 	Printf(format string, v ...any)
 	Print(v ...any)
 	Println(v ...any)
 }
+
+// Level represents logging level.
+//
+// Zero value is LevelDebug, thus printf Logger writes all messages by default. Levels keep log/slog gaps and differ
+// from log/slog levels by constant offset.
+type Level int
+
+const (
+	LevelDebug Level = 0
+	LevelInfo  Level = 4
+	LevelWarn  Level = 8
+	LevelError Level = 12
+)
+
+// Field represents structured logging field.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// StructLogger is the interface of structured logging.
+type StructLogger interface {
+	// Enabled checks if messages of given level must be logged.
+	Enabled(level Level) bool
+	// Log writes message with fields.
+	Log(level Level, msg string, fields ...Field)
+}
+
+// PrintfLogger is a shim that converts structured messages to printf Logger calls.
+type PrintfLogger struct {
+	l     Logger
+	level Level
+}
+
+// NewPrintfLogger makes new shim over printf logger l that writes messages of level and above.
+func NewPrintfLogger(l Logger, level Level) *PrintfLogger {
+	return &PrintfLogger{l: l, level: level}
+}
+
+func (l *PrintfLogger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *PrintfLogger) Log(level Level, msg string, fields ...Field) {
+	var buf strings.Builder
+	buf.WriteString(level.String())
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+	for i := 0; i < len(fields); i++ {
+		buf.WriteByte(' ')
+		buf.WriteString(fields[i].Key)
+		buf.WriteByte('=')
+		buf.WriteString(fieldValue(fields[i].Value))
+	}
+	buf.WriteByte('\n')
+	l.l.Print(buf.String())
+}
+
+// String returns level name.
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Check if logger l writes messages of given level.
+func logEnabled(l StructLogger, level Level) bool {
+	return l != nil && l.Enabled(level)
+}
+
+// Convert field value to string.
+func fieldValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case error:
+		return strconv.Quote(x.Error())
+	case interface{ String() string }:
+		return x.String()
+	case int:
+		return strconv.Itoa(x)
+	case int8:
+		return strconv.FormatInt(int64(x), 10)
+	case int16:
+		return strconv.FormatInt(int64(x), 10)
+	case int32:
+		return strconv.FormatInt(int64(x), 10)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		return "?"
+	}
+}
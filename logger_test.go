@@ -0,0 +1,32 @@
+package cbytecache
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	t.Run("printf", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := NewPrintfLogger(log.New(&buf, "", 0), LevelInfo)
+		if l.Enabled(LevelDebug) {
+			t.Error("debug level must be disabled")
+		}
+		l.Log(LevelError, "eviction failed", Field{"bucket", uint32(3)}, Field{"op", "evict"},
+			Field{"error", errors.New("fail")}, Field{"duration", time.Second})
+		assertString(t, "ERROR eviction failed bucket=3 op=\"evict\" error=\"fail\" duration=1s\n", buf.String())
+	})
+	t.Run("default level", func(t *testing.T) {
+		var buf bytes.Buffer
+		var conf Config
+		l := NewPrintfLogger(log.New(&buf, "", 0), conf.LogLevel)
+		if !l.Enabled(LevelDebug) {
+			t.Error("debug level must be enabled by default")
+		}
+		l.Log(LevelDebug, "evict entries", Field{"count", int64(-1)}, Field{"ratio", .5}, Field{"force", true})
+		assertString(t, "DEBUG evict entries count=-1 ratio=0.5 force=true\n", buf.String())
+	})
+}
@@ -36,3 +36,53 @@ type MetricsWriter interface {
 	// Load registers how many entries loaded from dump.
 	Load(bucket string)
 }
+
+// ListenerMetricsWriter is an optional interface of MetricsWriter that collects metrics of listeners (expire listener,
+// event listener and mutation stream).
+type ListenerMetricsWriter interface {
+	// ListenerError registers how many listener calls failed.
+	ListenerError(bucket string)
+	// ListenerDrop registers how many expired entries dropped due to full listener buffer.
+	ListenerDrop(bucket string)
+}
+
+// MissingMetricsWriter is an optional interface of MetricsWriter that collects metrics of known missing entries (see
+// Cache.SetMissing).
+type MissingMetricsWriter interface {
+	// SetMissing registers how many entries marks as known missing.
+	SetMissing(bucket string)
+	// HitMissing registers how many reads hit known missing entries.
+	HitMissing(bucket string)
+}
+
+// NamespaceMetricsWriter is an optional interface of MetricsWriter that collects metrics of cache namespaces.
+//
+// Namespace operations register in bucket metrics as well, see Cache.Namespace.
+type NamespaceMetricsWriter interface {
+	// NamespaceSet registers how many entries writes to the namespace.
+	NamespaceSet(namespace string, dur time.Duration)
+	// NamespaceHit registers how many entries reads from the namespace.
+	NamespaceHit(namespace string, dur time.Duration)
+	// NamespaceDel registers how many entries deletes from the namespace.
+	NamespaceDel(namespace string)
+	// NamespaceMiss registers how many namespace reads failed due to not found error.
+	NamespaceMiss(namespace string)
+	// NamespaceNoSpace registers how many namespace writes failed due to quota exceeding.
+	NamespaceNoSpace(namespace string)
+}
+
+// Get listener metrics writer of mw or dummy writer if mw doesn't implement ListenerMetricsWriter.
+func listenerMetricsOf(mw MetricsWriter) ListenerMetricsWriter {
+	if lmw, ok := mw.(ListenerMetricsWriter); ok {
+		return lmw
+	}
+	return dummyMetrics
+}
+
+// Get missing metrics writer of mw or dummy writer if mw doesn't implement MissingMetricsWriter.
+func missingMetricsOf(mw MetricsWriter) MissingMetricsWriter {
+	if mmw, ok := mw.(MissingMetricsWriter); ok {
+		return mmw
+	}
+	return dummyMetrics
+}
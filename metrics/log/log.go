@@ -49,6 +49,14 @@ func (m LogMetrics) Miss(bucket string) {
 	log.Printf("cbytecache %s: cache miss in bucket %s\n", m.key, bucket)
 }
 
+func (m LogMetrics) SetMissing(bucket string) {
+	log.Printf("cbytecache %s: entry marked as missing in bucket %s\n", m.key, bucket)
+}
+
+func (m LogMetrics) HitMissing(bucket string) {
+	log.Printf("cbytecache %s: missing entry hit in bucket %s\n", m.key, bucket)
+}
+
 func (m LogMetrics) Hit(bucket string, dur time.Duration) {
 	log.Printf("cbytecache %s: cache hit in bucket %s took %s\n", m.key, bucket, dur)
 }
@@ -77,4 +85,32 @@ func (m LogMetrics) Load(bucket string) {
 	log.Printf("cbytecache %s: load dumped entry to bucket #%s\n", m.key, bucket)
 }
 
+func (m LogMetrics) ListenerError(bucket string) {
+	log.Printf("cbytecache %s: expire listener failed on entry of bucket #%s\n", m.key, bucket)
+}
+
+func (m LogMetrics) ListenerDrop(bucket string) {
+	log.Printf("cbytecache %s: expire listener dropped entry of bucket #%s\n", m.key, bucket)
+}
+
+func (m LogMetrics) NamespaceSet(namespace string, dur time.Duration) {
+	log.Printf("cbytecache %s: set new entry to namespace %s took %s\n", m.key, namespace, dur)
+}
+
+func (m LogMetrics) NamespaceHit(namespace string, dur time.Duration) {
+	log.Printf("cbytecache %s: cache hit in namespace %s took %s\n", m.key, namespace, dur)
+}
+
+func (m LogMetrics) NamespaceDel(namespace string) {
+	log.Printf("cbytecache %s: delete entry from namespace %s\n", m.key, namespace)
+}
+
+func (m LogMetrics) NamespaceMiss(namespace string) {
+	log.Printf("cbytecache %s: cache miss in namespace %s\n", m.key, namespace)
+}
+
+func (m LogMetrics) NamespaceNoSpace(namespace string) {
+	log.Printf("cbytecache %s: quota exceeded in namespace %s\n", m.key, namespace)
+}
+
 var _ = NewLogMetrics
@@ -0,0 +1,290 @@
+package cbytecache
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	cacheTotal       = "total"
+	cacheUsed        = "used"
+	cacheFree        = "free"
+	cacheEntryTotal  = "entry_total"
+	cacheEntryDelete = "entry_delete"
+
+	cacheIOSet       = "set"
+	cacheIOEvict     = "evict"
+	cacheIOMiss      = "miss"
+	cacheIOHit       = "hit"
+	cacheIODel       = "del"
+	cacheIOExpire    = "expire"
+	cacheIOCorrupt   = "corrupt"
+	cacheIOCollision = "collision"
+	cacheIONoSpace   = "no space"
+
+	cacheIOSetMissing = "set missing"
+	cacheIOHitMissing = "hit missing"
+
+	speedWrite = "write"
+	speedRead  = "read"
+
+	arenaTotal = "total"
+	arenaUsed  = "used"
+	arenaFree  = "free"
+
+	arenaIOAlloc   = "alloc"
+	arenaIORelease = "release"
+	arenaIOReset   = "reset"
+	arenaIOFill    = "fill"
+
+	dumpIODump = "dump"
+	dumpIOLoad = "load"
+
+	listenerIOError = "error"
+	listenerIODrop  = "drop"
+
+	attrCache     = "cache"
+	attrBucket    = "bucket"
+	attrType      = "type"
+	attrOp        = "op"
+	attrNamespace = "namespace"
+)
+
+// OTelMetrics is an OpenTelemetry implementation of cbytecache.MetricsWriter.
+//
+// Instruments names and attributes repeat names and labels of Prometheus implementation.
+type OTelMetrics struct {
+	key  string
+	prec time.Duration
+
+	size, arena         metric.Int64UpDownCounter
+	io, arenaIO, dumpIO metric.Int64Counter
+	listenerIO          metric.Int64Counter
+	speed               metric.Float64Histogram
+	nsIO                metric.Int64Counter
+	nsSpeed             metric.Float64Histogram
+}
+
+var _ = NewOTelMetrics
+
+func NewOTelMetrics(key string, meter metric.Meter) (*OTelMetrics, error) {
+	return NewOTelMetricsWP(key, meter, time.Nanosecond)
+}
+
+func NewOTelMetricsWP(key string, meter metric.Meter, precision time.Duration) (*OTelMetrics, error) {
+	if precision == 0 {
+		precision = time.Nanosecond
+	}
+	m := &OTelMetrics{
+		key:  key,
+		prec: precision,
+	}
+
+	var err error
+	if m.size, err = meter.Int64UpDownCounter("cbytecache_size",
+		metric.WithDescription("Total, used and free cache (bucket) size in bytes."),
+		metric.WithUnit("By")); err != nil {
+		return nil, err
+	}
+	if m.io, err = meter.Int64Counter("cbytecache_io",
+		metric.WithDescription("Count cache IO operations calls.")); err != nil {
+		return nil, err
+	}
+
+	if m.arena, err = meter.Int64UpDownCounter("cbytecache_arena",
+		metric.WithDescription("Arenas count in cache (bucket).")); err != nil {
+		return nil, err
+	}
+	if m.arenaIO, err = meter.Int64Counter("cbytecache_arena_io",
+		metric.WithDescription("Count arena IO operations calls.")); err != nil {
+		return nil, err
+	}
+
+	if m.dumpIO, err = meter.Int64Counter("cbytecache_dump",
+		metric.WithDescription("Count dump IO operations calls.")); err != nil {
+		return nil, err
+	}
+
+	if m.listenerIO, err = meter.Int64Counter("cbytecache_listener",
+		metric.WithDescription("Count expire listener failures and drops.")); err != nil {
+		return nil, err
+	}
+
+	speedBuckets := []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 15, 20, 30, 40, 50, 100, 150, 200, 250,
+		500, 1000, 1500, 2000, 3000, 5000}
+	if m.speed, err = meter.Float64Histogram("cbytecache_io_speed",
+		metric.WithDescription("Cache IO operations speed."),
+		metric.WithExplicitBucketBoundaries(speedBuckets...)); err != nil {
+		return nil, err
+	}
+
+	if m.nsIO, err = meter.Int64Counter("cbytecache_namespace_io",
+		metric.WithDescription("Count cache namespace IO operations calls.")); err != nil {
+		return nil, err
+	}
+	if m.nsSpeed, err = meter.Float64Histogram("cbytecache_namespace_io_speed",
+		metric.WithDescription("Cache namespace IO operations speed."),
+		metric.WithExplicitBucketBoundaries(speedBuckets...)); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m OTelMetrics) Alloc(bucket string, size uint32) {
+	m.size.Add(ctx, int64(size), m.attrT(bucket, cacheTotal))
+	m.size.Add(ctx, int64(size), m.attrT(bucket, cacheFree))
+
+	m.arena.Add(ctx, 1, m.attrT(bucket, arenaTotal))
+	m.arena.Add(ctx, 1, m.attrT(bucket, arenaFree))
+	m.arenaIO.Add(ctx, 1, m.attrOp(bucket, arenaIOAlloc))
+}
+
+func (m OTelMetrics) Fill(bucket string, size uint32) {
+	m.size.Add(ctx, int64(size), m.attrT(bucket, cacheUsed))
+	m.size.Add(ctx, -int64(size), m.attrT(bucket, cacheFree))
+
+	m.arena.Add(ctx, 1, m.attrT(bucket, arenaUsed))
+	m.arena.Add(ctx, -1, m.attrT(bucket, arenaFree))
+	m.arenaIO.Add(ctx, 1, m.attrOp(bucket, arenaIOFill))
+}
+
+func (m OTelMetrics) Reset(bucket string, size uint32) {
+	m.size.Add(ctx, -int64(size), m.attrT(bucket, cacheUsed))
+	m.size.Add(ctx, int64(size), m.attrT(bucket, cacheFree))
+
+	m.arena.Add(ctx, -1, m.attrT(bucket, arenaUsed))
+	m.arena.Add(ctx, 1, m.attrT(bucket, arenaFree))
+	m.arenaIO.Add(ctx, 1, m.attrOp(bucket, arenaIOReset))
+}
+
+func (m OTelMetrics) Release(bucket string, size uint32) {
+	m.size.Add(ctx, -int64(size), m.attrT(bucket, cacheTotal))
+	m.size.Add(ctx, -int64(size), m.attrT(bucket, cacheFree))
+
+	m.arena.Add(ctx, -1, m.attrT(bucket, arenaTotal))
+	m.arena.Add(ctx, -1, m.attrT(bucket, arenaFree))
+	m.arenaIO.Add(ctx, 1, m.attrOp(bucket, arenaIORelease))
+}
+
+func (m OTelMetrics) Set(bucket string, dur time.Duration) {
+	m.size.Add(ctx, 1, m.attrT(bucket, cacheEntryTotal))
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOSet))
+	m.speed.Record(ctx, float64(dur.Nanoseconds()/int64(m.prec)), m.attrOp(bucket, speedWrite))
+}
+
+func (m OTelMetrics) Del(bucket string) {
+	m.size.Add(ctx, 1, m.attrT(bucket, cacheEntryDelete))
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIODel))
+}
+
+func (m OTelMetrics) Evict(bucket string, alive bool) {
+	m.size.Add(ctx, -1, m.attrT(bucket, cacheEntryTotal))
+	if !alive {
+		m.size.Add(ctx, -1, m.attrT(bucket, cacheEntryDelete))
+	}
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOEvict))
+}
+
+func (m OTelMetrics) Miss(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOMiss))
+}
+
+func (m OTelMetrics) SetMissing(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOSetMissing))
+}
+
+func (m OTelMetrics) HitMissing(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOHitMissing))
+}
+
+func (m OTelMetrics) Hit(bucket string, dur time.Duration) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOHit))
+	m.speed.Record(ctx, float64(dur.Nanoseconds()/int64(m.prec)), m.attrOp(bucket, speedRead))
+}
+
+func (m OTelMetrics) Expire(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOExpire))
+}
+
+func (m OTelMetrics) Corrupt(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOCorrupt))
+}
+
+func (m OTelMetrics) Collision(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIOCollision))
+}
+
+func (m OTelMetrics) NoSpace(bucket string) {
+	m.io.Add(ctx, 1, m.attrOp(bucket, cacheIONoSpace))
+}
+
+func (m OTelMetrics) Dump(bucket string) {
+	m.dumpIO.Add(ctx, 1, m.attrOp(bucket, dumpIODump))
+}
+
+func (m OTelMetrics) Load(bucket string) {
+	m.dumpIO.Add(ctx, 1, m.attrOp(bucket, dumpIOLoad))
+}
+
+func (m OTelMetrics) ListenerError(bucket string) {
+	m.listenerIO.Add(ctx, 1, m.attrOp(bucket, listenerIOError))
+}
+
+func (m OTelMetrics) ListenerDrop(bucket string) {
+	m.listenerIO.Add(ctx, 1, m.attrOp(bucket, listenerIODrop))
+}
+
+func (m OTelMetrics) NamespaceSet(namespace string, dur time.Duration) {
+	m.nsIO.Add(ctx, 1, m.attrNS(namespace, cacheIOSet))
+	m.nsSpeed.Record(ctx, float64(dur.Nanoseconds()/int64(m.prec)), m.attrNS(namespace, speedWrite))
+}
+
+func (m OTelMetrics) NamespaceHit(namespace string, dur time.Duration) {
+	m.nsIO.Add(ctx, 1, m.attrNS(namespace, cacheIOHit))
+	m.nsSpeed.Record(ctx, float64(dur.Nanoseconds()/int64(m.prec)), m.attrNS(namespace, speedRead))
+}
+
+func (m OTelMetrics) NamespaceDel(namespace string) {
+	m.nsIO.Add(ctx, 1, m.attrNS(namespace, cacheIODel))
+}
+
+func (m OTelMetrics) NamespaceMiss(namespace string) {
+	m.nsIO.Add(ctx, 1, m.attrNS(namespace, cacheIOMiss))
+}
+
+func (m OTelMetrics) NamespaceNoSpace(namespace string) {
+	m.nsIO.Add(ctx, 1, m.attrNS(namespace, cacheIONoSpace))
+}
+
+// Build attributes set with type attribute.
+func (m OTelMetrics) attrT(bucket, typ string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String(attrCache, m.key),
+		attribute.String(attrBucket, bucket),
+		attribute.String(attrType, typ),
+	)
+}
+
+// Build attributes set with operation attribute.
+func (m OTelMetrics) attrOp(bucket, op string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String(attrCache, m.key),
+		attribute.String(attrBucket, bucket),
+		attribute.String(attrOp, op),
+	)
+}
+
+// Build attributes set with namespace and operation attributes.
+func (m OTelMetrics) attrNS(namespace, op string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String(attrCache, m.key),
+		attribute.String(attrNamespace, namespace),
+		attribute.String(attrOp, op),
+	)
+}
+
+var ctx = context.Background()
@@ -0,0 +1,122 @@
+package cbytecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOTelMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	m, err := NewOTelMetrics("test", provider.Meter("cbytecache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Alloc("0", 1024)
+	m.Alloc("0", 1024)
+	m.Fill("0", 1024)
+	m.Set("0", time.Microsecond)
+	m.Set("0", time.Microsecond)
+	m.Hit("0", time.Microsecond)
+	m.Miss("1")
+	m.Del("0")
+	m.Evict("0", false)
+
+	var rm metricdata.ResourceMetrics
+	if err = reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("size", func(t *testing.T) {
+		assertSum(t, &rm, "cbytecache_size", "0", attrType, cacheTotal, 2048)
+		assertSum(t, &rm, "cbytecache_size", "0", attrType, cacheUsed, 1024)
+		assertSum(t, &rm, "cbytecache_size", "0", attrType, cacheFree, 1024)
+		assertSum(t, &rm, "cbytecache_size", "0", attrType, cacheEntryTotal, 1)
+		assertSum(t, &rm, "cbytecache_size", "0", attrType, cacheEntryDelete, 0)
+	})
+	t.Run("arena", func(t *testing.T) {
+		assertSum(t, &rm, "cbytecache_arena", "0", attrType, arenaTotal, 2)
+		assertSum(t, &rm, "cbytecache_arena", "0", attrType, arenaUsed, 1)
+		assertSum(t, &rm, "cbytecache_arena", "0", attrType, arenaFree, 1)
+		assertSum(t, &rm, "cbytecache_arena_io", "0", attrOp, arenaIOAlloc, 2)
+	})
+	t.Run("io", func(t *testing.T) {
+		assertSum(t, &rm, "cbytecache_io", "0", attrOp, cacheIOSet, 2)
+		assertSum(t, &rm, "cbytecache_io", "0", attrOp, cacheIOHit, 1)
+		assertSum(t, &rm, "cbytecache_io", "1", attrOp, cacheIOMiss, 1)
+	})
+	t.Run("speed", func(t *testing.T) {
+		assertHist(t, &rm, "cbytecache_io_speed", "0", speedWrite, 2)
+		assertHist(t, &rm, "cbytecache_io_speed", "0", speedRead, 1)
+	})
+}
+
+func findMetric(rm *metricdata.ResourceMetrics, name string) *metricdata.Metrics {
+	for i := range rm.ScopeMetrics {
+		for j := range rm.ScopeMetrics[i].Metrics {
+			if m := &rm.ScopeMetrics[i].Metrics[j]; m.Name == name {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+func matchAttrs(set attribute.Set, bucket, key, val string) bool {
+	c, _ := set.Value(attrCache)
+	b, _ := set.Value(attrBucket)
+	v, _ := set.Value(attribute.Key(key))
+	return c.AsString() == "test" && b.AsString() == bucket && v.AsString() == val
+}
+
+func assertSum(t *testing.T, rm *metricdata.ResourceMetrics, name, bucket, key, val string, expect int64) {
+	t.Helper()
+	m := findMetric(rm, name)
+	if m == nil {
+		t.Errorf("metric %s not found", name)
+		return
+	}
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	if !ok {
+		t.Errorf("metric %s has unexpected type %T", name, m.Data)
+		return
+	}
+	for _, dp := range sum.DataPoints {
+		if matchAttrs(dp.Attributes, bucket, key, val) {
+			if dp.Value != expect {
+				t.Errorf("metric %s{%s=%s} mismatch: need %d got %d", name, key, val, expect, dp.Value)
+			}
+			return
+		}
+	}
+	t.Errorf("metric %s{%s=%s} not found", name, key, val)
+}
+
+func assertHist(t *testing.T, rm *metricdata.ResourceMetrics, name, bucket, op string, expect uint64) {
+	t.Helper()
+	m := findMetric(rm, name)
+	if m == nil {
+		t.Errorf("metric %s not found", name)
+		return
+	}
+	hist, ok := m.Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Errorf("metric %s has unexpected type %T", name, m.Data)
+		return
+	}
+	for _, dp := range hist.DataPoints {
+		if matchAttrs(dp.Attributes, bucket, attrOp, op) {
+			if dp.Count != expect {
+				t.Errorf("metric %s{op=%s} count mismatch: need %d got %d", name, op, expect, dp.Count)
+			}
+			return
+		}
+	}
+	t.Errorf("metric %s{op=%s} not found", name, op)
+}
@@ -1,6 +1,8 @@
 package cbytecache
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -23,6 +25,9 @@ const (
 	cacheIOCollision = "collision"
 	cacheIONoSpace   = "no space"
 
+	cacheIOSetMissing = "set missing"
+	cacheIOHitMissing = "hit missing"
+
 	speedWrite = "write"
 	speedRead  = "read"
 
@@ -37,155 +42,383 @@ const (
 
 	dumpIODump = "dump"
 	dumpIOLoad = "load"
+
+	listenerIOError = "error"
+	listenerIODrop  = "drop"
+
+	defaultNamespace = "cbytecache"
+	labelCache       = "cache"
+	labelNamespace   = "namespace"
 )
 
+// PrometheusConfig describes Prometheus metrics writer properties.
+type PrometheusConfig struct {
+	// Key represents cache name. Uses as value of "cache" label.
+	Key string
+	// Precision of speed histograms.
+	// If this param omit time.Nanosecond will use instead.
+	Precision time.Duration
+	// Registerer to register metrics in.
+	// If this param omit prometheus.DefaultRegisterer will use instead.
+	Registerer prometheus.Registerer
+	// Namespace and Subsystem are prefixes of metrics names.
+	// If Namespace omit "cbytecache" will use instead.
+	Namespace, Subsystem string
+	// ConstLabels applies to all metrics.
+	ConstLabels prometheus.Labels
+}
+
 // PrometheusMetrics is a Prometheus implementation of cbytecache.MetricsWriter.
+//
+// Several instances with the same registerer, prefixes and const labels share the same collector.
 type PrometheusMetrics struct {
 	key  string
 	prec time.Duration
+	reg  prometheus.Registerer
+	c    *collector
+	// Close flag, shared among copies of the writer.
+	closed *uint32
+}
+
+// Internal collector contains metrics vectors.
+type collector struct {
+	size, arena         *prometheus.GaugeVec
+	io, arenaIO, dumpIO *prometheus.CounterVec
+	listenerIO          *prometheus.CounterVec
+	speed               *prometheus.HistogramVec
+	nsIO                *prometheus.CounterVec
+	nsSpeed             *prometheus.HistogramVec
+
+	// Count of metrics writers uses the collector.
+	refs int
+	// Count of metrics writers per cache key.
+	keys map[string]int
 }
 
 var (
-	promSize, promArena             *prometheus.GaugeVec
-	promIO, promArenaIO, promDumpIO *prometheus.CounterVec
-	promSpeed                       *prometheus.HistogramVec
+	// Protects collector registration and unregistration.
+	regMux sync.Mutex
 
-	_ = NewPrometheusMetrics
+	_, _, _ = NewPrometheusMetrics, NewPrometheusMetricsWP, NewPrometheusMetricsWithConfig
 )
 
-func init() {
-	promSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "cbytecache_size",
-		Help: "Total, used and free cache (bucket) size in bytes.",
-	}, []string{"cache", "bucket", "type"})
-	promIO = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "cbytecache_io",
-		Help: "Count cache IO operations calls.",
-	}, []string{"cache", "bucket", "op"})
-
-	promArena = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "cbytecache_arena",
-		Help: "Arenas count in cache (bucket).",
-	}, []string{"cache", "bucket", "type"})
-	promArenaIO = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "cbytecache_arena_io",
-		Help: "Count arena IO operations calls.",
-	}, []string{"cache", "bucket", "op"})
-
-	promDumpIO = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "cbytecache_dump",
-		Help: "Count dump IO operations calls.",
-	}, []string{"cache", "bucket", "op"})
-
-	speedBuckets := append(prometheus.DefBuckets, []float64{15, 20, 30, 40, 50, 100, 150, 200, 250, 500, 1000, 1500, 2000, 3000, 5000}...)
-	promSpeed = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "cbytecache_io_speed",
-		Help:    "Cache IO operations speed.",
-		Buckets: speedBuckets,
-	}, []string{"cache", "bucket", "op"})
-
-	prometheus.MustRegister(promSize, promIO, promDumpIO, promArena, promArenaIO, promSpeed)
-}
-
 func NewPrometheusMetrics(key string) *PrometheusMetrics {
 	return NewPrometheusMetricsWP(key, time.Nanosecond)
 }
 
 func NewPrometheusMetricsWP(key string, precision time.Duration) *PrometheusMetrics {
-	if precision == 0 {
-		precision = time.Nanosecond
+	m, err := NewPrometheusMetricsWithConfig(PrometheusConfig{
+		Key:       key,
+		Precision: precision,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// NewPrometheusMetricsWithConfig makes metrics writer according config and registers its collector in registerer.
+func NewPrometheusMetricsWithConfig(conf PrometheusConfig) (*PrometheusMetrics, error) {
+	if conf.Precision == 0 {
+		conf.Precision = time.Nanosecond
+	}
+	if conf.Registerer == nil {
+		conf.Registerer = prometheus.DefaultRegisterer
+	}
+	if len(conf.Namespace) == 0 {
+		conf.Namespace = defaultNamespace
+	}
+	c, err := register(conf.Registerer, newCollector(&conf), conf.Key)
+	if err != nil {
+		return nil, err
 	}
 	m := &PrometheusMetrics{
-		key:  key,
-		prec: precision,
+		key:    conf.Key,
+		prec:   conf.Precision,
+		reg:    conf.Registerer,
+		c:      c,
+		closed: new(uint32),
 	}
-	return m
+	return m, nil
 }
 
 func (m PrometheusMetrics) Alloc(bucket string, size uint32) {
-	promSize.WithLabelValues(m.key, bucket, cacheTotal).Add(float64(size))
-	promSize.WithLabelValues(m.key, bucket, cacheFree).Add(float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheTotal).Add(float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheFree).Add(float64(size))
 
-	promArena.WithLabelValues(m.key, bucket, arenaTotal).Inc()
-	promArena.WithLabelValues(m.key, bucket, arenaFree).Inc()
-	promArenaIO.WithLabelValues(m.key, bucket, arenaIOAlloc).Inc()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaTotal).Inc()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaFree).Inc()
+	m.c.arenaIO.WithLabelValues(m.key, bucket, arenaIOAlloc).Inc()
 }
 
 func (m PrometheusMetrics) Fill(bucket string, size uint32) {
-	promSize.WithLabelValues(m.key, bucket, cacheUsed).Add(float64(size))
-	promSize.WithLabelValues(m.key, bucket, cacheFree).Add(-float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheUsed).Add(float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheFree).Add(-float64(size))
 
-	promArena.WithLabelValues(m.key, bucket, arenaUsed).Inc()
-	promArena.WithLabelValues(m.key, bucket, arenaFree).Dec()
-	promArenaIO.WithLabelValues(m.key, bucket, arenaIOFill).Inc()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaUsed).Inc()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaFree).Dec()
+	m.c.arenaIO.WithLabelValues(m.key, bucket, arenaIOFill).Inc()
 }
 
 func (m PrometheusMetrics) Reset(bucket string, size uint32) {
-	promSize.WithLabelValues(m.key, bucket, cacheUsed).Add(-float64(size))
-	promSize.WithLabelValues(m.key, bucket, cacheFree).Add(float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheUsed).Add(-float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheFree).Add(float64(size))
 
-	promArena.WithLabelValues(m.key, bucket, arenaUsed).Dec()
-	promArena.WithLabelValues(m.key, bucket, arenaFree).Inc()
-	promArenaIO.WithLabelValues(m.key, bucket, arenaIOReset).Inc()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaUsed).Dec()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaFree).Inc()
+	m.c.arenaIO.WithLabelValues(m.key, bucket, arenaIOReset).Inc()
 }
 
 func (m PrometheusMetrics) Release(bucket string, size uint32) {
-	promSize.WithLabelValues(m.key, bucket, cacheTotal).Add(-float64(size))
-	promSize.WithLabelValues(m.key, bucket, cacheFree).Add(-float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheTotal).Add(-float64(size))
+	m.c.size.WithLabelValues(m.key, bucket, cacheFree).Add(-float64(size))
 
-	promArena.WithLabelValues(m.key, bucket, arenaTotal).Dec()
-	promArena.WithLabelValues(m.key, bucket, arenaFree).Dec()
-	promArenaIO.WithLabelValues(m.key, bucket, arenaIORelease).Inc()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaTotal).Dec()
+	m.c.arena.WithLabelValues(m.key, bucket, arenaFree).Dec()
+	m.c.arenaIO.WithLabelValues(m.key, bucket, arenaIORelease).Inc()
 }
 
 func (m PrometheusMetrics) Set(bucket string, dur time.Duration) {
-	promSize.WithLabelValues(m.key, bucket, cacheEntryTotal).Inc()
-	promIO.WithLabelValues(m.key, bucket, cacheIOSet).Inc()
-	promSpeed.WithLabelValues(m.key, bucket, speedWrite).Observe(float64(dur.Nanoseconds() / int64(m.prec)))
+	m.c.size.WithLabelValues(m.key, bucket, cacheEntryTotal).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOSet).Inc()
+	m.c.speed.WithLabelValues(m.key, bucket, speedWrite).Observe(float64(dur.Nanoseconds() / int64(m.prec)))
 }
 
 func (m PrometheusMetrics) Del(bucket string) {
-	promSize.WithLabelValues(m.key, bucket, cacheEntryDelete).Inc()
-	promIO.WithLabelValues(m.key, bucket, cacheIODel).Inc()
+	m.c.size.WithLabelValues(m.key, bucket, cacheEntryDelete).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIODel).Inc()
 }
 
 func (m PrometheusMetrics) Evict(bucket string, alive bool) {
-	promSize.WithLabelValues(m.key, bucket, cacheEntryTotal).Dec()
+	m.c.size.WithLabelValues(m.key, bucket, cacheEntryTotal).Dec()
 	if !alive {
-		promSize.WithLabelValues(m.key, bucket, cacheEntryDelete).Dec()
+		m.c.size.WithLabelValues(m.key, bucket, cacheEntryDelete).Dec()
 	}
-	promIO.WithLabelValues(m.key, bucket, cacheIOEvict).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOEvict).Inc()
 }
 
 func (m PrometheusMetrics) Miss(bucket string) {
-	promIO.WithLabelValues(m.key, bucket, cacheIOMiss).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOMiss).Inc()
+}
+
+func (m PrometheusMetrics) SetMissing(bucket string) {
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOSetMissing).Inc()
+}
+
+func (m PrometheusMetrics) HitMissing(bucket string) {
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOHitMissing).Inc()
 }
 
 func (m PrometheusMetrics) Hit(bucket string, dur time.Duration) {
-	promIO.WithLabelValues(m.key, bucket, cacheIOHit).Inc()
-	promSpeed.WithLabelValues(m.key, bucket, speedRead).Observe(float64(dur.Nanoseconds() / int64(m.prec)))
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOHit).Inc()
+	m.c.speed.WithLabelValues(m.key, bucket, speedRead).Observe(float64(dur.Nanoseconds() / int64(m.prec)))
 }
 
 func (m PrometheusMetrics) Expire(bucket string) {
-	promIO.WithLabelValues(m.key, bucket, cacheIOExpire).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOExpire).Inc()
 }
 
 func (m PrometheusMetrics) Corrupt(bucket string) {
-	promIO.WithLabelValues(m.key, bucket, cacheIOCorrupt).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOCorrupt).Inc()
 }
 
 func (m PrometheusMetrics) Collision(bucket string) {
-	promIO.WithLabelValues(m.key, bucket, cacheIOCollision).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIOCollision).Inc()
 }
 
 func (m PrometheusMetrics) NoSpace(bucket string) {
-	promIO.WithLabelValues(m.key, bucket, cacheIONoSpace).Inc()
+	m.c.io.WithLabelValues(m.key, bucket, cacheIONoSpace).Inc()
 }
 
 func (m PrometheusMetrics) Dump(bucket string) {
-	promDumpIO.WithLabelValues(m.key, bucket, dumpIODump).Inc()
+	m.c.dumpIO.WithLabelValues(m.key, bucket, dumpIODump).Inc()
 }
 
 func (m PrometheusMetrics) Load(bucket string) {
-	promDumpIO.WithLabelValues(m.key, bucket, dumpIOLoad).Inc()
+	m.c.dumpIO.WithLabelValues(m.key, bucket, dumpIOLoad).Inc()
+}
+
+func (m PrometheusMetrics) ListenerError(bucket string) {
+	m.c.listenerIO.WithLabelValues(m.key, bucket, listenerIOError).Inc()
+}
+
+func (m PrometheusMetrics) ListenerDrop(bucket string) {
+	m.c.listenerIO.WithLabelValues(m.key, bucket, listenerIODrop).Inc()
+}
+
+func (m PrometheusMetrics) NamespaceSet(namespace string, dur time.Duration) {
+	m.c.nsIO.WithLabelValues(m.key, namespace, cacheIOSet).Inc()
+	m.c.nsSpeed.WithLabelValues(m.key, namespace, speedWrite).Observe(float64(dur.Nanoseconds() / int64(m.prec)))
+}
+
+func (m PrometheusMetrics) NamespaceHit(namespace string, dur time.Duration) {
+	m.c.nsIO.WithLabelValues(m.key, namespace, cacheIOHit).Inc()
+	m.c.nsSpeed.WithLabelValues(m.key, namespace, speedRead).Observe(float64(dur.Nanoseconds() / int64(m.prec)))
+}
+
+func (m PrometheusMetrics) NamespaceDel(namespace string) {
+	m.c.nsIO.WithLabelValues(m.key, namespace, cacheIODel).Inc()
+}
+
+func (m PrometheusMetrics) NamespaceMiss(namespace string) {
+	m.c.nsIO.WithLabelValues(m.key, namespace, cacheIOMiss).Inc()
+}
+
+func (m PrometheusMetrics) NamespaceNoSpace(namespace string) {
+	m.c.nsIO.WithLabelValues(m.key, namespace, cacheIONoSpace).Inc()
+}
+
+// Close removes all cache metrics from the registerer.
+//
+// Cache metrics remove after close of the last metrics writer with the same key and collector unregisters after close
+// of the last metrics writer that uses it. Cache calls this method on close.
+func (m PrometheusMetrics) Close() error {
+	if m.c == nil || !atomic.CompareAndSwapUint32(m.closed, 0, 1) {
+		return nil
+	}
+	regMux.Lock()
+	defer regMux.Unlock()
+	if m.c.refs--; m.c.refs == 0 {
+		m.reg.Unregister(m.c)
+		return nil
+	}
+	if m.c.keys[m.key]--; m.c.keys[m.key] == 0 {
+		delete(m.c.keys, m.key)
+		m.c.deleteCache(m.key)
+	}
+	return nil
+}
+
+// Make new collector according config.
+func newCollector(conf *PrometheusConfig) *collector {
+	ns, ss, cl := conf.Namespace, conf.Subsystem, conf.ConstLabels
+	c := &collector{keys: make(map[string]int)}
+	c.size = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "size",
+		Help:        "Total, used and free cache (bucket) size in bytes.",
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "type"})
+	c.io = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "io",
+		Help:        "Count cache IO operations calls.",
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "op"})
+
+	c.arena = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "arena",
+		Help:        "Arenas count in cache (bucket).",
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "type"})
+	c.arenaIO = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "arena_io",
+		Help:        "Count arena IO operations calls.",
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "op"})
+
+	c.dumpIO = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "dump",
+		Help:        "Count dump IO operations calls.",
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "op"})
+
+	c.listenerIO = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "listener",
+		Help:        "Count expire listener failures and drops.",
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "op"})
+
+	speedBuckets := append(prometheus.DefBuckets, []float64{15, 20, 30, 40, 50, 100, 150, 200, 250, 500, 1000, 1500, 2000, 3000, 5000}...)
+	c.speed = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "io_speed",
+		Help:        "Cache IO operations speed.",
+		Buckets:     speedBuckets,
+		ConstLabels: cl,
+	}, []string{labelCache, "bucket", "op"})
+
+	c.nsIO = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "namespace_io",
+		Help:        "Count cache namespace IO operations calls.",
+		ConstLabels: cl,
+	}, []string{labelCache, labelNamespace, "op"})
+	c.nsSpeed = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   ns,
+		Subsystem:   ss,
+		Name:        "namespace_io_speed",
+		Help:        "Cache namespace IO operations speed.",
+		Buckets:     speedBuckets,
+		ConstLabels: cl,
+	}, []string{labelCache, labelNamespace, "op"})
+	return c
+}
+
+// Register collector in reg or reuse already registered one.
+func register(reg prometheus.Registerer, c *collector, key string) (*collector, error) {
+	regMux.Lock()
+	defer regMux.Unlock()
+	if err := reg.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		if c, ok = are.ExistingCollector.(*collector); !ok {
+			return nil, err
+		}
+	}
+	c.refs++
+	c.keys[key]++
+	return c, nil
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	c.size.Describe(ch)
+	c.io.Describe(ch)
+	c.arena.Describe(ch)
+	c.arenaIO.Describe(ch)
+	c.dumpIO.Describe(ch)
+	c.listenerIO.Describe(ch)
+	c.speed.Describe(ch)
+	c.nsIO.Describe(ch)
+	c.nsSpeed.Describe(ch)
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.size.Collect(ch)
+	c.io.Collect(ch)
+	c.arena.Collect(ch)
+	c.arenaIO.Collect(ch)
+	c.dumpIO.Collect(ch)
+	c.listenerIO.Collect(ch)
+	c.speed.Collect(ch)
+	c.nsIO.Collect(ch)
+	c.nsSpeed.Collect(ch)
+}
+
+// Remove all metrics of given cache.
+func (c *collector) deleteCache(key string) {
+	l := prometheus.Labels{labelCache: key}
+	c.size.DeletePartialMatch(l)
+	c.io.DeletePartialMatch(l)
+	c.arena.DeletePartialMatch(l)
+	c.arenaIO.DeletePartialMatch(l)
+	c.dumpIO.DeletePartialMatch(l)
+	c.listenerIO.DeletePartialMatch(l)
+	c.speed.DeletePartialMatch(l)
+	c.nsIO.DeletePartialMatch(l)
+	c.nsSpeed.DeletePartialMatch(l)
 }
@@ -0,0 +1,90 @@
+package cbytecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Run("registry", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m, err := NewPrometheusMetricsWithConfig(PrometheusConfig{
+			Key:         "test",
+			Registerer:  reg,
+			Subsystem:   "l1",
+			ConstLabels: prometheus.Labels{"service": "foo"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Set("0", time.Microsecond)
+		mf, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasFamily(mf, "cbytecache_l1_io") {
+			t.Error("metric cbytecache_l1_io not found")
+		}
+		_ = m.Close()
+		if mf, _ = reg.Gather(); len(mf) != 0 {
+			t.Errorf("registry must be empty after close, got %d families", len(mf))
+		}
+	})
+	t.Run("shared", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m0, err := NewPrometheusMetricsWithConfig(PrometheusConfig{Key: "c0", Registerer: reg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m1, err := NewPrometheusMetricsWithConfig(PrometheusConfig{Key: "c1", Registerer: reg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m0.Miss("0")
+		m1.Miss("0")
+		_ = m0.Close()
+		mf, _ := reg.Gather()
+		if n := countSeries(mf, "cbytecache_io"); n != 1 {
+			t.Errorf("series count mismatch: need 1 got %d", n)
+		}
+		_ = m1.Close()
+		if mf, _ = reg.Gather(); len(mf) != 0 {
+			t.Errorf("registry must be empty after close, got %d families", len(mf))
+		}
+	})
+	t.Run("same key", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m0, _ := NewPrometheusMetricsWithConfig(PrometheusConfig{Key: "c0", Registerer: reg})
+		m1, _ := NewPrometheusMetricsWithConfig(PrometheusConfig{Key: "c0", Registerer: reg})
+		m2, _ := NewPrometheusMetricsWithConfig(PrometheusConfig{Key: "c1", Registerer: reg})
+		m0.Miss("0")
+		m2.Miss("0")
+		_ = m1.Close()
+		_ = m1.Close()
+		mf, _ := reg.Gather()
+		if n := countSeries(mf, "cbytecache_io"); n != 2 {
+			t.Errorf("series count mismatch: need 2 got %d", n)
+		}
+		_ = m0.Close()
+		_ = m2.Close()
+		if mf, _ = reg.Gather(); len(mf) != 0 {
+			t.Errorf("registry must be empty after close, got %d families", len(mf))
+		}
+	})
+}
+
+func hasFamily(mf []*dto.MetricFamily, name string) bool {
+	return countSeries(mf, name) > 0
+}
+
+func countSeries(mf []*dto.MetricFamily, name string) int {
+	for _, f := range mf {
+		if f.GetName() == name {
+			return len(f.GetMetric())
+		}
+	}
+	return 0
+}
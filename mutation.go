@@ -0,0 +1,131 @@
+package cbytecache
+
+// MutationType represents type of cache mutation.
+type MutationType uint8
+
+const (
+	// MutationSet triggers on every written entry (Set, SetMarshallerTo, SetMany, refresh, ...).
+	MutationSet MutationType = iota
+	// MutationDelete triggers on explicit delete or extract of the entry.
+	MutationDelete
+	// MutationExpire triggers on expired entries during eviction.
+	MutationExpire
+)
+
+// Mutation represents single change of the cache.
+type Mutation struct {
+	Type  MutationType
+	Entry Entry
+}
+
+// MutationStream is the interface that wraps the basic Push method.
+//
+// Push calls synchronously under bucket lock, so implementation must be fast (see queue.MutationStream).
+// Mutation entry data is valid only inside the call, use Entry.Copy to keep it.
+type MutationStream interface {
+	Push(m Mutation) error
+}
+
+// String returns mutation type name.
+func (t MutationType) String() string {
+	switch t {
+	case MutationSet:
+		return "set"
+	case MutationDelete:
+		return "delete"
+	case MutationExpire:
+		return "expire"
+	default:
+		return "unknown"
+	}
+}
+
+// SetEntry sets entry to the cache with its absolute expire timestamp.
+//
+// Zero expire means default expiration (see Config.ExpireInterval). Already expired entry returns ErrEntryExpired.
+// Unlike Set, existing entry will overwrite.
+func (c *Cache) SetEntry(e Entry) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if err := c.checkEntry(e.Key, uint32(len(e.Body))); err != nil {
+		return err
+	}
+	if len(e.Tags) > MaxTags {
+		return ErrTooManyTags
+	}
+	if e.Expire > 0 && e.Expire < c.now() {
+		return ErrEntryExpired
+	}
+	tn, err := c.tenantOf(e.Key)
+	if err != nil {
+		return err
+	}
+	h := nsHash(c.config.Hasher.Sum64(e.Key), e.Namespace)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.apply(&e, h, tn, false)
+}
+
+// Apply applies mutation received from another cache instance (see Config.MutationStream).
+//
+// Set mutations write entries with original expire timestamp, delete and expire mutations remove entries. Applied
+// mutations don't trigger mutation stream and listeners, thus replication loops are impossible.
+func (c *Cache) Apply(m Mutation) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	switch m.Type {
+	case MutationSet:
+		err := c.SetEntry(m.Entry)
+		if err == ErrEntryExpired {
+			err = ErrOK
+		}
+		return err
+	case MutationDelete, MutationExpire:
+		h := nsHash(c.config.Hasher.Sum64(m.Entry.Key), m.Entry.Namespace)
+		bkt := c.buckets[h%uint64(c.config.Buckets)]
+		return bkt.apply(&m.Entry, h, 0, true)
+	default:
+		return ErrBadMutation
+	}
+}
+
+// Apply single entry change silently: without mutations and events.
+//
+// Entry e writes on behalf of tenant tn or deletes if del flag enabled.
+func (b *bucket) apply(e *Entry, h uint64, tn uint16, del bool) (err error) {
+	if err = b.checkStatus(); err != nil {
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	_ = b.delLF(h, eventNone)
+	if del {
+		return
+	}
+	if err = b.setLF(e.Key, h, e.Body, e.Expire, e.Namespace, tn); err == nil {
+		b.tagLF(h, e.Tags)
+	}
+	return
+}
+
+// Send set mutation of the last written entry.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) mutateLastLF() {
+	if b.elen() == 0 {
+		return
+	}
+	b.mutateLF(&b.entry[b.elen()-1])
+}
+
+// Send set mutation of written entry e.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) mutateLF(e *entry) {
+	if b.config.MutationStream == nil {
+		return
+	}
+	b.event(e, eventSet)
+}
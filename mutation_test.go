@@ -0,0 +1,158 @@
+package cbytecache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+type testMutationStream struct {
+	mux sync.Mutex
+	m   []Mutation
+}
+
+func (s *testMutationStream) Push(m Mutation) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.m = append(s.m, Mutation{Type: m.Type, Entry: m.Entry.Copy()})
+	return nil
+}
+
+func (s *testMutationStream) flush() []Mutation {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	m := s.m
+	s.m = nil
+	return m
+}
+
+func TestMutationStream(t *testing.T) {
+	var s testMutationStream
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	conf.MutationStream = &s
+	src, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = src.Close() }()
+
+	conf1 := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf1.Clock = conf.Clock
+	dst, err := New(conf1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	apply := func() {
+		for _, m := range s.flush() {
+			if err := dst.Apply(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if err = src.Set(fmt.Sprintf("key%d", i), getEntryBody(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = src.Delete("key0")
+	_, _ = src.Extract("key1")
+	apply()
+	if _, err = dst.Get("key0"); err != ErrNotFound {
+		t.Errorf("key0 must be deleted, got %v", err)
+	}
+	if _, err = dst.Get("key1"); err != ErrNotFound {
+		t.Errorf("key1 must be deleted, got %v", err)
+	}
+	for i := 2; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		se, err := src.GetEntryTo(nil, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de, err := dst.GetEntryTo(nil, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBytes(t, se.Body, de.Body)
+		if se.Expire != de.Expire {
+			t.Errorf("expire mismatch: need %d got %d", se.Expire, de.Expire)
+		}
+	}
+
+	conf.Clock.Jump(time.Minute + time.Second)
+	time.Sleep(time.Millisecond * 5)
+	var n int
+	for _, m := range s.flush() {
+		if m.Type == MutationExpire {
+			n++
+		}
+	}
+	if n != 8 {
+		t.Errorf("expire mutations mismatch: need 8 got %d", n)
+	}
+
+	if err = dst.SetEntry(Entry{Key: "foo", Body: []byte("bar"), Expire: 1}); err != ErrEntryExpired {
+		t.Errorf("expired entry must be rejected, got %v", err)
+	}
+}
+
+func TestSetEntryExpire(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 1
+	conf.ArenaCapacity = Kilobyte
+	conf.Clock = clock.NewClock()
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	now := uint32(conf.Clock.Now().Unix())
+	if err = cache.SetEntry(Entry{Key: "long", Body: getEntryBody(0), Expire: now + 3600}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i))
+	}
+	if err = cache.SetEntry(Entry{Key: "short", Body: getEntryBody(0), Expire: now + 10}); err != nil {
+		t.Fatal(err)
+	}
+	_ = cache.Set("key3", getEntryBody(3))
+
+	// Short entry expires, but entries written before it keep.
+	conf.Clock.Jump(time.Second * 30)
+	_ = cache.Evict()
+	if _, err = cache.Get("short"); err != ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err = cache.Get(fmt.Sprintf("key%d", i)); err != nil {
+			t.Errorf("key%d: %v", i, err)
+		}
+	}
+
+	// Long entry survives eviction of entries written after it.
+	conf.Clock.Jump(time.Minute * 2)
+	for i := 0; i < 3; i++ {
+		_ = cache.Set(fmt.Sprintf("new%d", i), getEntryBody(i))
+		_ = cache.Evict()
+	}
+	body, err := cache.Get("long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBytes(t, getEntryBody(0), body)
+	// Long entry moved to the tail, so head arenas recycled.
+	b := cache.buckets[0]
+	if b.elen() > 8 {
+		t.Errorf("expired entries must be evicted, got %d entries", b.elen())
+	}
+}
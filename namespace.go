@@ -0,0 +1,243 @@
+package cbytecache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Namespace is a logical sub-cache that shares buckets, arenas and maintenance jobs with the parent cache.
+//
+// Keys of different namespaces don't intersect: namespace ID folds into key hash and stores together with entry key.
+// Namespace may have its own capacity quota, writes over the quota fail with ErrQuotaExceeded. Quota splits evenly
+// among buckets the same way as cache capacity does. Entries of namespace that exceeds decreased quota will delete
+// during the next eviction.
+type Namespace struct {
+	c     *Cache
+	id    uint16
+	name  string
+	quota uint64
+	mw    NamespaceMetricsWriter
+}
+
+// Registry of cache namespaces.
+type nsRegistry struct {
+	mux sync.RWMutex
+	idx map[string]*Namespace
+	// Namespaces ordered by ID.
+	buf []*Namespace
+}
+
+// Namespace returns namespace with given name or registers new one.
+//
+// Param quota limits memory size of namespace entries, zero means no limit. Quota of existing namespace will update.
+// Namespace ID derives from the name, so dumped or replicated entries get to the same namespaces regardless of
+// registration order. Returns ErrNamespaceClash if ID of the name is taken by other namespace, use other name then.
+func (c *Cache) Namespace(name string, quota MemorySize) (*Namespace, error) {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return nil, err
+	}
+	if len(name) == 0 {
+		return nil, ErrBadNamespace
+	}
+
+	r := &c.nsr
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if n, ok := r.idx[name]; ok {
+		n.setQuota(quota)
+		return n, ErrOK
+	}
+	id := nsID(name)
+	i := sort.Search(len(r.buf), func(i int) bool { return r.buf[i].id >= id })
+	if i < len(r.buf) && r.buf[i].id == id {
+		return nil, ErrNamespaceClash
+	}
+	if r.idx == nil {
+		r.idx = make(map[string]*Namespace)
+	}
+	n := &Namespace{
+		c:    c,
+		id:   id,
+		name: name,
+	}
+	var ok bool
+	if n.mw, ok = c.config.MetricsWriter.(NamespaceMetricsWriter); !ok {
+		n.mw = dummyMetrics
+	}
+	n.setQuota(quota)
+	r.idx[name] = n
+	r.buf = append(r.buf, nil)
+	copy(r.buf[i+1:], r.buf[i:])
+	r.buf[i] = n
+	return n, ErrOK
+}
+
+// Get namespace ID of the name: FNV-1a hash folded to non-zero uint16.
+//
+// Doesn't use Config.Hasher to keep ID the same in caches with different hashers.
+func nsID(name string) uint16 {
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	if id := uint16(h ^ h>>16); id != 0 {
+		return id
+	}
+	return 1
+}
+
+// Namespaces returns all registered namespaces ordered by ID.
+func (c *Cache) Namespaces() []*Namespace {
+	r := &c.nsr
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return append([]*Namespace(nil), r.buf...)
+}
+
+// ID returns namespace ID.
+func (n *Namespace) ID() uint16 {
+	return n.id
+}
+
+// Name returns namespace name.
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Quota returns namespace quota. Zero means no limit.
+func (n *Namespace) Quota() MemorySize {
+	return MemorySize(atomic.LoadUint64(&n.quota))
+}
+
+// Set sets entry bytes to the namespace.
+func (n *Namespace) Set(key string, data []byte) error {
+	c := n.c
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if err := c.checkEntry(key, uint32(len(data))); err != nil {
+		return err
+	}
+	tn, err := c.tenantOf(key)
+	if err != nil {
+		return err
+	}
+	stm := c.config.Clock.Now()
+	h := nsHash(c.config.Hasher.Sum64(key), n.id)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	err = bkt.set(key, h, data, n.id, tn, nil)
+	switch err {
+	case ErrOK:
+		n.mw.NamespaceSet(n.name, c.config.Clock.Now().Sub(stm))
+	case ErrQuotaExceeded:
+		n.mw.NamespaceNoSpace(n.name)
+	}
+	return err
+}
+
+// Get gets entry bytes by key.
+func (n *Namespace) Get(key string) ([]byte, error) {
+	return n.GetTo(nil, key)
+}
+
+// GetTo gets entry bytes to dst.
+func (n *Namespace) GetTo(dst []byte, key string) ([]byte, error) {
+	e, err := n.get(dst, key, false)
+	return e.Body, err
+}
+
+// GetEntryTo gets entry with key, body and expire timestamp. Body will write to dst.
+//
+// Entry key and body point to dst and stay valid until dst reuse.
+func (n *Namespace) GetEntryTo(dst []byte, key string) (Entry, error) {
+	return n.get(dst, key, false)
+}
+
+// Extract gets entry bytes by key and remove entry afterward.
+func (n *Namespace) Extract(key string) ([]byte, error) {
+	return n.ExtractTo(nil, key)
+}
+
+// ExtractTo gets entry bytes to dst and remove it afterward.
+func (n *Namespace) ExtractTo(dst []byte, key string) ([]byte, error) {
+	e, err := n.get(dst, key, true)
+	return e.Body, err
+}
+
+// Delete removes entry from namespace.
+func (n *Namespace) Delete(key string) error {
+	c := n.c
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	h := nsHash(c.config.Hasher.Sum64(key), n.id)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	err := bkt.del(h)
+	if err == nil {
+		n.mw.NamespaceDel(n.name)
+	}
+	return err
+}
+
+// Size returns namespace size snapshot.
+//
+// Used size contains size of alive namespace entries. Total size is a namespace quota or total cache size if
+// namespace has no quota.
+func (n *Namespace) Size() (r CacheSize) {
+	c := n.c
+	_ = c.buckets[len(c.buckets)-1]
+	for i := 0; i < len(c.buckets); i++ {
+		r.u += MemorySize(c.buckets[i].nsSize(n.id))
+		if n.Quota() == 0 {
+			t, _, _ := c.buckets[i].size.snapshot()
+			r.t += MemorySize(t)
+		}
+	}
+	if q := n.Quota(); q > 0 {
+		r.t = q
+	}
+	if r.t > r.u {
+		r.f = r.t - r.u
+	}
+	return
+}
+
+// Reset deletes all namespace entries.
+//
+// Unlike Cache.Reset, entries data keeps in arenas till the next eviction, but namespace quota releases immediately.
+func (n *Namespace) Reset() error {
+	return n.c.bulkExec(defaultResetWorkers, "reset namespace", func(b *bucket) error { return b.resetNS(n.id) })
+}
+
+// Internal getter.
+func (n *Namespace) get(dst []byte, key string, del bool) (Entry, error) {
+	c := n.c
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return Entry{Body: dst}, err
+	}
+	stm := c.config.Clock.Now()
+	h := nsHash(c.config.Hasher.Sum64(key), n.id)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	e, err := bkt.getEntry(dst, h, del, false)
+	switch {
+	case err == nil:
+		n.mw.NamespaceHit(n.name, c.config.Clock.Now().Sub(stm))
+	case err == ErrNotFound || err == ErrMissing:
+		n.mw.NamespaceMiss(n.name)
+	}
+	return e, err
+}
+
+// Set namespace quota and split it among buckets.
+func (n *Namespace) setQuota(quota MemorySize) {
+	c := n.c
+	atomic.StoreUint64(&n.quota, uint64(quota))
+	bq := bucketQuota(quota, c.config.Buckets)
+	for i := 0; i < len(c.buckets); i++ {
+		c.buckets[i].setNSQuota(n.id, bq)
+	}
+}
+
+var _ Interface = (*Namespace)(nil)
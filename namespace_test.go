@@ -0,0 +1,184 @@
+package cbytecache
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/hash/fnv"
+)
+
+type nsMetrics struct {
+	DummyMetrics
+	set, hit, miss, del, nospace map[string]int
+}
+
+func newNSMetrics() *nsMetrics {
+	return &nsMetrics{
+		set:     make(map[string]int),
+		hit:     make(map[string]int),
+		miss:    make(map[string]int),
+		del:     make(map[string]int),
+		nospace: make(map[string]int),
+	}
+}
+
+func (m *nsMetrics) NamespaceSet(ns string, _ time.Duration) { m.set[ns]++ }
+func (m *nsMetrics) NamespaceHit(ns string, _ time.Duration) { m.hit[ns]++ }
+func (m *nsMetrics) NamespaceDel(ns string)                  { m.del[ns]++ }
+func (m *nsMetrics) NamespaceMiss(ns string)                 { m.miss[ns]++ }
+func (m *nsMetrics) NamespaceNoSpace(ns string)              { m.nospace[ns]++ }
+
+type dumpBuf struct {
+	mux     sync.Mutex
+	entries []Entry
+}
+
+func (w *dumpBuf) Write(e Entry) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.entries = append(w.entries, e.Copy())
+	return e.Size(), nil
+}
+
+func (w *dumpBuf) Flush() error { return nil }
+
+func TestNamespace(t *testing.T) {
+	mw := newNSMetrics()
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 4
+	conf.MetricsWriter = mw
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	users, err := cache.Namespace("users", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := cache.Namespace("sessions", 4*Kilobyte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.ID() == 0 || users.ID() != nsID("users") || sessions.ID() != nsID("sessions") {
+		t.Errorf("ID mismatch: %d %d", users.ID(), sessions.ID())
+	}
+	if ns, _ := cache.Namespace("users", 0); ns != users {
+		t.Error("namespace must be registered once")
+	}
+	if _, err = cache.Namespace("", 0); err != ErrBadNamespace {
+		t.Errorf("error mismatch: need ErrBadNamespace got %v", err)
+	}
+	// Find name with the same ID.
+	for i := 0; ; i++ {
+		if name := "ns" + strconv.Itoa(i); nsID(name) == users.ID() {
+			if _, err = cache.Namespace(name, 0); err != ErrNamespaceClash {
+				t.Errorf("error mismatch: need ErrNamespaceClash got %v", err)
+			}
+			break
+		}
+	}
+
+	t.Run("isolation", func(t *testing.T) {
+		_ = cache.Set("foo", []byte("default"))
+		_ = users.Set("foo", []byte("user"))
+		_ = sessions.Set("foo", []byte("session"))
+		for _, tc := range []struct {
+			c    Interface
+			body string
+		}{{cache, "default"}, {users, "user"}, {sessions, "session"}} {
+			if b, err := tc.c.Get("foo"); err != nil || string(b) != tc.body {
+				t.Errorf("get mismatch: need %s got %s (%v)", tc.body, b, err)
+			}
+		}
+		e, err := users.GetEntryTo(nil, "foo")
+		if err != nil || e.Key != "foo" || e.Namespace != users.ID() {
+			t.Errorf("entry mismatch: %+v %v", e, err)
+		}
+		if err = users.Delete("foo"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = users.Get("foo"); err != ErrNotFound {
+			t.Errorf("error mismatch: need ErrNotFound got %v", err)
+		}
+		if b, _ := sessions.Get("foo"); string(b) != "session" {
+			t.Errorf("delete must not affect other namespaces, got %s", b)
+		}
+		if mw.set["users"] != 1 || mw.hit["users"] != 2 || mw.del["users"] != 1 || mw.miss["users"] != 1 {
+			t.Errorf("metrics mismatch: %+v", mw)
+		}
+	})
+	t.Run("quota", func(t *testing.T) {
+		body := bytes.Repeat([]byte("x"), 256)
+		var (
+			i   int
+			err error
+		)
+		for i = 0; i < 1000 && err == nil; i++ {
+			err = sessions.Set("key"+string(rune('a'+i%26))+string(rune('a'+i/26)), body)
+		}
+		if err != ErrQuotaExceeded {
+			t.Fatalf("error mismatch: need ErrQuotaExceeded got %v", err)
+		}
+		size := sessions.Size()
+		if size.Total() != 4*Kilobyte || size.Used() > size.Total() || size.Used() == 0 {
+			t.Errorf("size mismatch: %s", size)
+		}
+		if mw.nospace["sessions"] != 1 {
+			t.Errorf("no space metric mismatch: %d", mw.nospace["sessions"])
+		}
+		// Other namespaces aren't limited.
+		for j := 0; j < i; j++ {
+			if err := users.Set("key"+string(rune('a'+j%26))+string(rune('a'+j/26)), body); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	t.Run("reset", func(t *testing.T) {
+		if err := sessions.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if sessions.Size().Used() != 0 {
+			t.Errorf("used size must be zero after reset, got %d", sessions.Size().Used())
+		}
+		if _, err := sessions.Get("foo"); err != ErrNotFound {
+			t.Errorf("error mismatch: need ErrNotFound got %v", err)
+		}
+		if b, _ := cache.Get("foo"); string(b) != "default" {
+			t.Errorf("reset must not affect other namespaces, got %s", b)
+		}
+		if err := sessions.Set("foo", []byte("session")); err != nil {
+			t.Errorf("quota must release after reset: %v", err)
+		}
+	})
+	t.Run("dump", func(t *testing.T) {
+		var w dumpBuf
+		if err := cache.DumpTo(&w); err != nil {
+			t.Fatal(err)
+		}
+		conf1 := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+		conf1.Buckets = 4
+		cache1, err := New(conf1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache1.Close() }()
+		_, _ = cache1.Namespace("users", 0)
+		sessions1, _ := cache1.Namespace("sessions", 0)
+		for i := range w.entries {
+			if err = cache1.SetEntry(w.entries[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if b, _ := sessions1.Get("foo"); string(b) != "session" {
+			t.Errorf("dumped entry mismatch, got %s", b)
+		}
+		if b, _ := cache1.Get("foo"); string(b) != "default" {
+			t.Errorf("dumped entry mismatch, got %s", b)
+		}
+	})
+}
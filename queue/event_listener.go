@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"github.com/koykov/cbytecache"
+)
+
+// EventListener is a cbytecache.EventListener implementation that forwards events to the enqueuer.
+type EventListener struct {
+	enqueuer cbytecache.Enqueuer
+}
+
+// NewEventListener makes new EventListener instance with given enqueuer.
+func NewEventListener(enq cbytecache.Enqueuer) (*EventListener, error) {
+	if enq == nil {
+		return nil, cbytecache.ErrNoEnqueuer
+	}
+	q := EventListener{enqueuer: enq}
+	return &q, nil
+}
+
+func (q *EventListener) OnEvent(event cbytecache.Event) error {
+	if q.enqueuer == nil {
+		return cbytecache.ErrNoEnqueuer
+	}
+	cpy := cbytecache.Event{Type: event.Type, Entry: event.Entry.Copy()}
+	return q.enqueuer.Enqueue(cpy)
+}
+
+var _ = NewEventListener
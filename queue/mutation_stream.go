@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"github.com/koykov/cbytecache"
+)
+
+// MutationStream is a cbytecache.MutationStream implementation that forwards mutations to the enqueuer.
+type MutationStream struct {
+	enqueuer cbytecache.Enqueuer
+}
+
+// NewMutationStream makes new MutationStream instance with given enqueuer.
+func NewMutationStream(enq cbytecache.Enqueuer) (*MutationStream, error) {
+	if enq == nil {
+		return nil, cbytecache.ErrNoEnqueuer
+	}
+	q := MutationStream{enqueuer: enq}
+	return &q, nil
+}
+
+func (q *MutationStream) Push(m cbytecache.Mutation) error {
+	if q.enqueuer == nil {
+		return cbytecache.ErrNoEnqueuer
+	}
+	cpy := cbytecache.Mutation{Type: m.Type, Entry: m.Entry.Copy()}
+	return q.enqueuer.Enqueue(cpy)
+}
+
+var _ = NewMutationStream
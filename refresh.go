@@ -0,0 +1,143 @@
+package cbytecache
+
+import (
+	"sync"
+)
+
+// Refresher is the interface that wraps the basic Refresh method.
+//
+// Refresh calls for entries near expiration (see Config.RefreshWindow) and must return fresh entry body that will
+// replace the entry. Fresh body drops if the entry was deleted or changed during the call. Entry data is a copy and
+// may be kept.
+type Refresher interface {
+	Refresh(entry Entry) ([]byte, error)
+}
+
+// Bounded pool of refresh workers.
+type refreshPool struct {
+	cache *Cache
+	queue chan refreshTask
+	wg    sync.WaitGroup
+
+	mux sync.Mutex
+	// Hashes of entries waiting for refresh.
+	pending map[uint64]struct{}
+	closed  bool
+}
+
+// Single refresh task.
+type refreshTask struct {
+	hash  uint64
+	entry Entry
+}
+
+// Make and start refresh pool.
+func newRefreshPool(c *Cache, workers, size uint) *refreshPool {
+	p := refreshPool{
+		cache:   c,
+		queue:   make(chan refreshTask, size),
+		pending: make(map[uint64]struct{}),
+	}
+	for i := uint(0); i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return &p
+}
+
+// Try to enqueue entry for refresh. Returns false if queue is full.
+func (p *refreshPool) enqueue(h uint64, e Entry) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.closed {
+		return false
+	}
+	if _, ok := p.pending[h]; ok {
+		return true
+	}
+	select {
+	case p.queue <- refreshTask{hash: h, entry: e.Copy()}:
+		p.pending[h] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// Refresh worker.
+func (p *refreshPool) work() {
+	defer p.wg.Done()
+	c := p.cache
+	for t := range p.queue {
+		body, err := c.config.Refresher.Refresh(t.entry)
+		if err == nil {
+			bkt := c.buckets[t.hash%uint64(c.config.Buckets)]
+			// Entry deleted or changed during the refresh keeps as is.
+			if err = bkt.replace(t.entry.Key, t.hash, body, t.entry.Version); err == ErrEntryVersion {
+				err = nil
+			}
+		}
+		if err != nil && logEnabled(c.l(), LevelWarn) {
+			c.l().Log(LevelWarn, "entry refresh failed",
+				Field{"op", "refresh"}, Field{"key", t.entry.Key}, Field{"error", err})
+		}
+		p.mux.Lock()
+		delete(p.pending, t.hash)
+		p.mux.Unlock()
+	}
+}
+
+// Stop workers and wait for tasks in progress.
+func (p *refreshPool) stop() {
+	p.mux.Lock()
+	if p.closed {
+		p.mux.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.mux.Unlock()
+	p.wg.Wait()
+}
+
+// Enqueue entries that expire within refresh window. Returns count of enqueued and dropped entries.
+//
+// It works in lock-free mode thus need to guarantee thread-safety outside.
+func (b *bucket) refreshLF() (rc, dc int) {
+	el := b.elen()
+	if b.rp == nil || el == 0 {
+		return
+	}
+	buf := b.entry
+	now := b.now()
+	hi := now + uint32(b.config.RefreshWindow.Seconds())
+	_ = buf[el-1]
+	// Expire order of entries isn't guaranteed (see Cache.SetEntry), so check every entry.
+	inWindow := func(e *entry) bool {
+		return !e.invalid() && e.expire >= now && e.expire <= hi
+	}
+	for i := 0; i < int(el); i++ {
+		e := &buf[i]
+		if !inWindow(e) {
+			continue
+		}
+		b.buf.ResetLen()
+		_ = b.buf.GrowLen(int(e.length))
+		key, body, err := b.getLF(b.buf.Bytes()[:0], e, dummyMetrics)
+		if err != nil {
+			continue
+		}
+		if !b.rp.enqueue(e.hash, Entry{Key: key, Body: body, Expire: e.expire, Namespace: e.ns, Version: e.ver, Tags: b.copyTagsLF(e.hash)}) {
+			// Queue is full, skip the rest until the next eviction.
+			for ; i < int(el); i++ {
+				if inWindow(&buf[i]) {
+					dc++
+				}
+			}
+			break
+		}
+		rc++
+	}
+	b.buf.ResetLen()
+	return
+}
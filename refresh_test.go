@@ -0,0 +1,163 @@
+package cbytecache
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+type testRefresher struct {
+	c uint32
+}
+
+func (r *testRefresher) Refresh(entry Entry) ([]byte, error) {
+	atomic.AddUint32(&r.c, 1)
+	n, err := strconv.Atoi(entry.Key[3:])
+	if err != nil {
+		return nil, err
+	}
+	return getEntryBody(n + 1), nil
+}
+
+func TestRefresh(t *testing.T) {
+	const count = 100
+
+	var r testRefresher
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Clock = clock.NewClock()
+	conf.EvictInterval = time.Second * 10
+	conf.Refresher = &r
+	conf.RefreshWindow = time.Second * 15
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		if err = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Entries are out of refresh window.
+	conf.Clock.Jump(time.Second * 10)
+	time.Sleep(time.Millisecond * 5)
+	if c := atomic.LoadUint32(&r.c); c != 0 {
+		t.Errorf("refresh calls mismatch: need 0 got %d", c)
+	}
+	// Entries are within refresh window.
+	conf.Clock.Jump(time.Second * 40)
+	time.Sleep(time.Millisecond * 20)
+	if c := atomic.LoadUint32(&r.c); c != count {
+		t.Errorf("refresh calls mismatch: need %d got %d", count, c)
+	}
+	// Refreshed entries survive original expiration.
+	conf.Clock.Jump(time.Second * 20)
+	time.Sleep(time.Millisecond * 5)
+	for i := 0; i < count; i++ {
+		body, err := cache.Get(fmt.Sprintf("key%d", i))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		assertBytes(t, getEntryBody(i+1), body)
+	}
+	_ = cache.Close()
+}
+
+type blockRefresher struct {
+	release chan struct{}
+}
+
+func (r *blockRefresher) Refresh(Entry) ([]byte, error) {
+	<-r.release
+	return nil, ErrNotFound
+}
+
+func TestRefreshDrop(t *testing.T) {
+	r := blockRefresher{release: make(chan struct{})}
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 1
+	conf.Clock = clock.NewClock()
+	conf.Refresher = &r
+	conf.RefreshWindow = time.Second * 15
+	conf.RefreshWorkers = 1
+	conf.RefreshQueueSize = 1
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	defer close(r.release)
+
+	// Entries within refresh window.
+	for i := 0; i < 4; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i))
+	}
+	conf.Clock.Jump(time.Second * 30)
+	// Entries out of refresh window.
+	for i := 4; i < 20; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), getEntryBody(i))
+	}
+	conf.Clock.Jump(time.Second * 20)
+
+	b := cache.buckets[0]
+	b.mux.Lock()
+	rc, dc := b.refreshLF()
+	b.mux.Unlock()
+	if rc+dc != 4 || dc == 0 {
+		t.Errorf("refresh counters mismatch: enqueued %d, dropped %d", rc, dc)
+	}
+
+	conf1 := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf1.Refresher = &r
+	if _, err = New(conf1); err != ErrRefreshWindow {
+		t.Errorf("error mismatch: need ErrRefreshWindow got %v", err)
+	}
+}
+
+type gateRefresher struct {
+	enter, release chan struct{}
+}
+
+func (r *gateRefresher) Refresh(Entry) ([]byte, error) {
+	r.enter <- struct{}{}
+	<-r.release
+	return []byte("fresh"), nil
+}
+
+func TestRefreshDeleted(t *testing.T) {
+	r := gateRefresher{enter: make(chan struct{}), release: make(chan struct{})}
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 1
+	conf.Clock = clock.NewClock()
+	conf.Refresher = &r
+	conf.RefreshWindow = time.Second * 15
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	_ = cache.Set("foo", []byte("stale"))
+	_ = cache.Set("bar", []byte("stale"))
+	conf.Clock.Jump(time.Second * 50)
+	_ = cache.Evict()
+	// Entries deleted or changed during the refresh must keep as is.
+	<-r.enter
+	_ = cache.Delete("foo")
+	_ = cache.Delete("bar")
+	_ = cache.Set("bar", []byte("new"))
+	close(r.release)
+	<-r.enter
+	time.Sleep(time.Millisecond * 5)
+	if _, err = cache.Get("foo"); err != ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+	if b, _ := cache.Get("bar"); string(b) != "new" {
+		t.Errorf("body mismatch: need new got %s", b)
+	}
+}
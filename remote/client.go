@@ -0,0 +1,148 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/koykov/cbytecache"
+)
+
+var ErrBadURL = errors.New("bad service URL")
+
+// Client is a cbytecache.Interface implementation over remote Handler.
+type Client struct {
+	base string
+	hc   *http.Client
+}
+
+// NewClient makes new client of the service on given base URL.
+//
+// Pass http.Client with configured transport to use HTTP/2 (see http.Transport.ForceAttemptHTTP2), http.DefaultClient
+// will use if hc is nil.
+func NewClient(base string, hc *http.Client) (*Client, error) {
+	if _, err := url.Parse(base); err != nil || len(base) == 0 {
+		return nil, ErrBadURL
+	}
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	c := Client{
+		base: strings.TrimRight(base, "/"),
+		hc:   hc,
+	}
+	return &c, nil
+}
+
+func (c *Client) Set(key string, data []byte) error {
+	resp, err := c.do(http.MethodPut, keysPrefix+url.PathEscape(key), data)
+	if err != nil {
+		return err
+	}
+	return c.close(resp)
+}
+
+func (c *Client) Get(key string) ([]byte, error) {
+	return c.GetTo(nil, key)
+}
+
+func (c *Client) GetTo(dst []byte, key string) ([]byte, error) {
+	resp, err := c.do(http.MethodGet, keysPrefix+url.PathEscape(key), nil)
+	if err != nil {
+		return dst, err
+	}
+	return c.read(dst, resp)
+}
+
+func (c *Client) Delete(key string) error {
+	resp, err := c.do(http.MethodDelete, keysPrefix+url.PathEscape(key), nil)
+	if err != nil {
+		return err
+	}
+	return c.close(resp)
+}
+
+func (c *Client) Extract(key string) ([]byte, error) {
+	resp, err := c.do(http.MethodPost, extractPrefix+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.read(nil, resp)
+}
+
+// Size returns remote cache size. Zero size returns on any error.
+func (c *Client) Size() cbytecache.CacheSize {
+	resp, err := c.do(http.MethodGet, sizePath, nil)
+	if err != nil {
+		return cbytecache.CacheSize{}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var sz Size
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&sz) != nil {
+		return cbytecache.CacheSize{}
+	}
+	return cbytecache.NewCacheSize(cbytecache.MemorySize(sz.Total), cbytecache.MemorySize(sz.Used),
+		cbytecache.MemorySize(sz.Free))
+}
+
+func (c *Client) do(method, path string, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.base+path, r)
+	if err != nil {
+		return nil, err
+	}
+	return c.hc.Do(req)
+}
+
+// Read response body to dst.
+func (c *Client) read(dst []byte, resp *http.Response) ([]byte, error) {
+	defer func() { _ = resp.Body.Close() }()
+	if err := respError(resp); err != nil {
+		return dst, err
+	}
+	return readAll(dst, resp.Body)
+}
+
+// Drain and close response body.
+func (c *Client) close(resp *http.Response) error {
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return respError(resp)
+}
+
+// Restore cache error of the response.
+func respError(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	msg := resp.Header.Get(headerError)
+	if err, ok := knownErrors[msg]; ok {
+		return err
+	}
+	if len(msg) == 0 {
+		msg = resp.Status
+	}
+	return errors.New(msg)
+}
+
+// Cache errors that may be returned by remote service.
+var knownErrors = func() map[string]error {
+	m := make(map[string]error)
+	for _, err := range []error{
+		cbytecache.ErrNotFound, cbytecache.ErrMissing, cbytecache.ErrEntryExists, cbytecache.ErrEntryCollision, cbytecache.ErrEntryEmpty,
+		cbytecache.ErrKeyTooBig, cbytecache.ErrEntryTooBig, cbytecache.ErrNoSpace, cbytecache.ErrBucketService,
+		cbytecache.ErrCacheClosed,
+	} {
+		m[err.Error()] = err
+	}
+	return m
+}()
+
+var _ cbytecache.Interface = (*Client)(nil)
@@ -0,0 +1,225 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/koykov/cbytecache"
+)
+
+const (
+	keysPrefix    = "/keys/"
+	extractPrefix = "/extract/"
+	sizePath      = "/size"
+
+	// Header contains cache error message.
+	headerError = "X-Cache-Error"
+
+	// Default limit of request body if cache has no max entry size.
+	defaultMaxBodySize = 64 * cbytecache.Megabyte
+)
+
+var ErrKeyMissing = errors.New("key missing")
+
+// Size is a JSON representation of the cache size.
+type Size struct {
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+	Free  uint64 `json:"free"`
+}
+
+// Handler is a http.Handler implementation that exposes cbytecache.Interface:
+// * PUT /keys/{key} - set entry from request body
+// * GET /keys/{key} - get entry body
+// * DELETE /keys/{key} - delete entry
+// * POST /extract/{key} - get entry body and delete the entry
+// * GET /size - cache size
+//
+// Handler works over any HTTP version, HTTP/2 negotiates over TLS by net/http server.
+type Handler struct {
+	cache cbytecache.Interface
+	pool  sync.Pool
+	max   int64
+}
+
+// NewHandler makes new Handler instance with given cache.
+func NewHandler(cache cbytecache.Interface) (*Handler, error) {
+	if cache == nil {
+		return nil, cbytecache.ErrBadCache
+	}
+	h := Handler{cache: cache, max: int64(defaultMaxBodySize)}
+	if c, ok := cache.(*cbytecache.Cache); ok && c.MaxEntrySize() > 0 {
+		h.max = int64(c.MaxEntrySize())
+	}
+	return &h, nil
+}
+
+// WithMaxBodySize limits size of request body. Bigger requests reject with 413 status.
+// By default, limit is cache max entry size or 64MB if cache has no limit.
+func (h *Handler) WithMaxBodySize(size cbytecache.MemorySize) *Handler {
+	if size > 0 {
+		h.max = int64(size)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, keysPrefix):
+		key := path[len(keysPrefix):]
+		if len(key) == 0 {
+			h.error(w, http.StatusBadRequest, ErrKeyMissing)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.get(w, key, false)
+		case http.MethodPut:
+			h.set(w, r, key)
+		case http.MethodDelete:
+			if err := h.cache.Delete(key); err != nil {
+				h.error(w, status(err), err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			h.notAllowed(w, "GET, PUT, DELETE")
+		}
+	case strings.HasPrefix(path, extractPrefix):
+		key := path[len(extractPrefix):]
+		if len(key) == 0 {
+			h.error(w, http.StatusBadRequest, ErrKeyMissing)
+			return
+		}
+		if r.Method != http.MethodPost {
+			h.notAllowed(w, "POST")
+			return
+		}
+		h.get(w, key, true)
+	case path == sizePath:
+		if r.Method != http.MethodGet {
+			h.notAllowed(w, "GET")
+			return
+		}
+		sz := h.cache.Size()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Size{
+			Total: uint64(sz.Total()),
+			Used:  uint64(sz.Used()),
+			Free:  uint64(sz.Free()),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Get or extract entry.
+func (h *Handler) get(w http.ResponseWriter, key string, extract bool) {
+	buf := h.buf()
+	defer func() { h.pool.Put(buf[:0]) }()
+
+	var err error
+	if extract {
+		// Interface has no ExtractTo, so use it if implementation provides.
+		if x, ok := h.cache.(interface {
+			ExtractTo([]byte, string) ([]byte, error)
+		}); ok {
+			buf, err = x.ExtractTo(buf[:0], key)
+		} else {
+			buf, err = h.cache.Extract(key)
+		}
+	} else {
+		buf, err = h.cache.GetTo(buf[:0], key)
+	}
+	if err != nil {
+		h.error(w, status(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(buf)
+}
+
+// Set entry.
+func (h *Handler) set(w http.ResponseWriter, r *http.Request, key string) {
+	buf := h.buf()
+	defer func() { h.pool.Put(buf[:0]) }()
+
+	if r.ContentLength > h.max {
+		h.error(w, http.StatusRequestEntityTooLarge, cbytecache.ErrEntryTooBig)
+		return
+	}
+	var err error
+	if buf, err = readAll(buf[:0], http.MaxBytesReader(w, r.Body, h.max)); err != nil {
+		if int64(len(buf)) >= h.max {
+			// Body reached the limit.
+			h.error(w, http.StatusRequestEntityTooLarge, cbytecache.ErrEntryTooBig)
+			return
+		}
+		h.error(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = h.cache.Set(key, buf); err != nil {
+		h.error(w, status(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) buf() []byte {
+	if raw := h.pool.Get(); raw != nil {
+		return raw.([]byte)
+	}
+	return nil
+}
+
+func (h *Handler) notAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	h.error(w, http.StatusMethodNotAllowed, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+}
+
+func (h *Handler) error(w http.ResponseWriter, code int, err error) {
+	w.Header().Set(headerError, err.Error())
+	http.Error(w, err.Error(), code)
+}
+
+// Read all data from r and append to dst.
+func readAll(dst []byte, r io.Reader) ([]byte, error) {
+	for {
+		if len(dst) == cap(dst) {
+			dst = append(dst, 0)[:len(dst)]
+		}
+		n, err := r.Read(dst[len(dst):cap(dst)])
+		dst = dst[:len(dst)+n]
+		if err == io.EOF {
+			return dst, nil
+		}
+		if err != nil {
+			return dst, err
+		}
+	}
+}
+
+// Get HTTP status code of cache error.
+func status(err error) int {
+	switch err {
+	case cbytecache.ErrNotFound, cbytecache.ErrMissing:
+		return http.StatusNotFound
+	case cbytecache.ErrEntryExists, cbytecache.ErrEntryCollision:
+		return http.StatusConflict
+	case cbytecache.ErrEntryEmpty, cbytecache.ErrKeyTooBig:
+		return http.StatusBadRequest
+	case cbytecache.ErrEntryTooBig:
+		return http.StatusRequestEntityTooLarge
+	case cbytecache.ErrNoSpace:
+		return http.StatusInsufficientStorage
+	case cbytecache.ErrBucketService, cbytecache.ErrCacheClosed:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
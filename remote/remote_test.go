@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+func testInterface(t *testing.T, c cbytecache.Interface) {
+	if err := c.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("foo", []byte("bar")); err != cbytecache.ErrEntryExists {
+		t.Errorf("error mismatch: need ErrEntryExists got %v", err)
+	}
+	if err := c.Set("a/b c", []byte("qux")); err != nil {
+		t.Fatal(err)
+	}
+	body, err := c.Get("foo")
+	if err != nil || string(body) != "bar" {
+		t.Errorf("get mismatch: %s %v", body, err)
+	}
+	if body, err = c.GetTo([]byte("x"), "a/b c"); err != nil || string(body) != "xqux" {
+		t.Errorf("get to mismatch: %s %v", body, err)
+	}
+	if _, err = c.Get("missing"); err != cbytecache.ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+	if c.Size().Used() == 0 {
+		t.Error("used size must be greater than zero")
+	}
+	if body, err = c.Extract("foo"); err != nil || string(body) != "bar" {
+		t.Errorf("extract mismatch: %s %v", body, err)
+	}
+	if _, err = c.Get("foo"); err != cbytecache.ErrNotFound {
+		t.Errorf("extracted entry must be deleted, got %v", err)
+	}
+	if err = c.Delete("a/b c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Get("a/b c"); err != cbytecache.ErrNotFound {
+		t.Errorf("deleted entry must be missing, got %v", err)
+	}
+}
+
+func newCache(t *testing.T) *cbytecache.Cache {
+	cache, err := cbytecache.New(cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
+
+func TestRemote(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		testInterface(t, newCache(t))
+	})
+	t.Run("remote", func(t *testing.T) {
+		h, err := NewHandler(newCache(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var h1 int32
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor != 2 {
+				atomic.StoreInt32(&h1, 1)
+			}
+			h.ServeHTTP(w, r)
+		}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+
+		c, err := NewClient(srv.URL, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testInterface(t, c)
+		if atomic.LoadInt32(&h1) != 0 {
+			t.Error("HTTP/2 wasn't negotiated")
+		}
+	})
+	t.Run("body limit", func(t *testing.T) {
+		h, err := NewHandler(newCache(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.WithMaxBodySize(16)
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		c, err := NewClient(srv.URL, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Set("foo", make([]byte, 32)); err != cbytecache.ErrEntryTooBig {
+			t.Errorf("error mismatch: need ErrEntryTooBig got %v", err)
+		}
+		// Unknown body length.
+		req := httptest.NewRequest(http.MethodPut, "/keys/foo", io.MultiReader(strings.NewReader("foobar"), bytes.NewReader(make([]byte, 32))))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status mismatch: need 413 got %d", rec.Code)
+		}
+		if err = c.Set("foo", []byte("bar")); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package replication
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/koykov/byteconv"
+	"github.com/koykov/cbytecache"
+)
+
+// Message header: op (1 byte), expire (4 bytes), namespace ID (2 bytes), tags count (1 byte), key length (2 bytes),
+// body length (4 bytes).
+const headerSize = 14
+
+// Size of tag hash.
+const tagSize = 8
+
+// DefaultMaxFrameSize limits key and body length of received messages if other limit doesn't set.
+const DefaultMaxFrameSize = 64 * cbytecache.Megabyte
+
+var ErrFrameTooBig = errors.New("replication frame too big")
+
+// Conn is a Transport implementation over net.Conn (TCP, unix socket, ...).
+//
+// Messages encode to binary frames: header and following key, tags and body bytes.
+type Conn struct {
+	conn net.Conn
+	w    *bufio.Writer
+	r    *bufio.Reader
+	hdr  [headerSize]byte
+	buf  []byte
+	tags []uint64
+	max  uint64
+	once sync.Once
+}
+
+// NewConn makes new transport over given connection.
+func NewConn(conn net.Conn) *Conn {
+	c := Conn{
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+		r:    bufio.NewReader(conn),
+		max:  uint64(DefaultMaxFrameSize),
+	}
+	return &c
+}
+
+// SetMaxFrameSize limits total key and body length of messages. Send rejects such messages and Recv skips them, both
+// return ErrFrameTooBig, so malformed or hostile peer can't force huge allocations. The link stays usable after that.
+func (c *Conn) SetMaxFrameSize(size cbytecache.MemorySize) *Conn {
+	if size > 0 {
+		c.max = uint64(size)
+	}
+	return c
+}
+
+// Dial connects to replication source on given address and makes new transport over the connection.
+func Dial(network, addr string) (*Conn, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn), nil
+}
+
+func (c *Conn) Send(m Message) error {
+	e := &m.Entry
+	if len(e.Key) > cbytecache.MaxKeySize || uint64(len(e.Body)) > math.MaxUint32 || len(e.Tags) > cbytecache.MaxTags ||
+		uint64(len(e.Key)+len(e.Body)) > c.max {
+		return ErrFrameTooBig
+	}
+	c.hdr[0] = byte(m.Op)
+	binary.LittleEndian.PutUint32(c.hdr[1:5], e.Expire)
+	binary.LittleEndian.PutUint16(c.hdr[5:7], e.Namespace)
+	c.hdr[7] = byte(len(e.Tags))
+	binary.LittleEndian.PutUint16(c.hdr[8:10], uint16(len(e.Key)))
+	binary.LittleEndian.PutUint32(c.hdr[10:14], uint32(len(e.Body)))
+	if _, err := c.w.Write(c.hdr[:]); err != nil {
+		return err
+	}
+	if _, err := c.w.WriteString(e.Key); err != nil {
+		return err
+	}
+	var tag [tagSize]byte
+	for i := 0; i < len(e.Tags); i++ {
+		binary.LittleEndian.PutUint64(tag[:], e.Tags[i])
+		if _, err := c.w.Write(tag[:]); err != nil {
+			return err
+		}
+	}
+	_, err := c.w.Write(e.Body)
+	return err
+}
+
+func (c *Conn) Flush() error {
+	return c.w.Flush()
+}
+
+func (c *Conn) Recv() (m Message, err error) {
+	if _, err = io.ReadFull(c.r, c.hdr[:]); err != nil {
+		return
+	}
+	m.Op = Op(c.hdr[0])
+	m.Entry.Expire = binary.LittleEndian.Uint32(c.hdr[1:5])
+	m.Entry.Namespace = binary.LittleEndian.Uint16(c.hdr[5:7])
+	tl := int(c.hdr[7]) * tagSize
+	kl := int(binary.LittleEndian.Uint16(c.hdr[8:10]))
+	bl := int(binary.LittleEndian.Uint32(c.hdr[10:14]))
+	n := kl + tl + bl
+	if uint64(kl+bl) > c.max || tl > cbytecache.MaxTags*tagSize {
+		// Skip the frame to keep the link in sync.
+		if _, err = io.CopyN(io.Discard, c.r, int64(n)); err == nil {
+			err = ErrFrameTooBig
+		}
+		return
+	}
+	if cap(c.buf) < n {
+		c.buf = make([]byte, n)
+	}
+	c.buf = c.buf[:n]
+	if _, err = io.ReadFull(c.r, c.buf); err != nil {
+		return
+	}
+	m.Entry.Key = byteconv.B2S(c.buf[:kl])
+	c.tags = c.tags[:0]
+	for i := kl; i < kl+tl; i += tagSize {
+		c.tags = append(c.tags, binary.LittleEndian.Uint64(c.buf[i:]))
+	}
+	if len(c.tags) > 0 {
+		m.Entry.Tags = c.tags
+	}
+	m.Entry.Body = c.buf[kl+tl:]
+	return
+}
+
+func (c *Conn) Close() (err error) {
+	c.once.Do(func() { err = c.conn.Close() })
+	return
+}
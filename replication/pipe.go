@@ -0,0 +1,59 @@
+package replication
+
+import "sync"
+
+// Pipe is an in-process Transport implementation based on channel.
+type Pipe struct {
+	c    chan Message
+	once sync.Once
+	done chan struct{}
+}
+
+// NewPipe makes new in-process transport with given buffer size.
+//
+// The same instance must be passed both to Source.AddPeer and Replica.Serve.
+func NewPipe(size int) *Pipe {
+	p := Pipe{
+		c:    make(chan Message, size),
+		done: make(chan struct{}),
+	}
+	return &p
+}
+
+func (p *Pipe) Send(m Message) error {
+	select {
+	case <-p.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case p.c <- m:
+		return nil
+	case <-p.done:
+		return ErrClosed
+	}
+}
+
+func (p *Pipe) Flush() error {
+	return nil
+}
+
+func (p *Pipe) Recv() (Message, error) {
+	select {
+	case m := <-p.c:
+		return m, nil
+	case <-p.done:
+		// Deliver messages buffered before close.
+		select {
+		case m := <-p.c:
+			return m, nil
+		default:
+			return Message{}, ErrClosed
+		}
+	}
+}
+
+func (p *Pipe) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
@@ -0,0 +1,76 @@
+package replication
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/koykov/cbytecache"
+)
+
+// Replica applies changes received from replication source to the cache.
+//
+// Replica cache must not have Config.MutationStream pointing to the same source, since applied changes don't trigger
+// mutation stream anyway.
+type Replica struct {
+	cache *cbytecache.Cache
+	once  sync.Once
+	sync  chan struct{}
+	// Count of skipped messages.
+	skip uint64
+}
+
+// NewReplica makes new replica over given cache.
+func NewReplica(cache *cbytecache.Cache) (*Replica, error) {
+	if cache == nil {
+		return nil, ErrNoCache
+	}
+	r := Replica{
+		cache: cache,
+		sync:  make(chan struct{}),
+	}
+	return &r, nil
+}
+
+// Serve reads messages from the transport and applies them to the cache until transport closes.
+//
+// Serve returns error that interrupts the link (ErrClosed, io.EOF, ...).
+func (r *Replica) Serve(t Transport) error {
+	defer func() { _ = t.Close() }()
+	if c, ok := t.(*Conn); ok {
+		// Cache can't store messages bigger than max entry size anyway.
+		c.SetMaxFrameSize(r.cache.MaxEntrySize())
+	}
+	for {
+		msg, err := t.Recv()
+		if err == ErrFrameTooBig {
+			// Too big message skips, since it would come again after reconnect.
+			atomic.AddUint64(&r.skip, 1)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Op == OpSync {
+			r.once.Do(func() { close(r.sync) })
+			continue
+		}
+		m, err := mutationOf(msg)
+		if err != nil {
+			return err
+		}
+		// Errors of single entries (no space, too big, ...) don't break replication.
+		if err = r.cache.Apply(m); err == cbytecache.ErrCacheClosed || err == cbytecache.ErrBadCache {
+			return err
+		}
+	}
+}
+
+// Skipped returns count of messages skipped due to size limit (see Conn.SetMaxFrameSize).
+func (r *Replica) Skipped() uint64 {
+	return atomic.LoadUint64(&r.skip)
+}
+
+// Synced returns channel that closes after initial sync completes.
+func (r *Replica) Synced() <-chan struct{} {
+	return r.sync
+}
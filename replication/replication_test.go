@@ -0,0 +1,201 @@
+package replication
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+func newCache(t *testing.T, ms cbytecache.MutationStream) *cbytecache.Cache {
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.MutationStream = ms
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
+
+func waitFor(t *testing.T, fn func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout exceeded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testReplication(t *testing.T, connect func(src *Source, rep *Replica)) {
+	src := NewSource(0)
+	defer func() { _ = src.Close() }()
+	master := newCache(t, src)
+	if err := src.Bind(master); err != nil {
+		t.Fatal(err)
+	}
+	slave := newCache(t, nil)
+	rep, err := NewReplica(slave)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries written before the peer connects will deliver by initial sync.
+	for i := 0; i < 100; i++ {
+		_ = master.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("body%d", i)))
+	}
+	connect(src, rep)
+	select {
+	case <-rep.Synced():
+	case <-time.After(5 * time.Second):
+		t.Fatal("initial sync timeout")
+	}
+
+	for i := 100; i < 200; i++ {
+		_ = master.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("body%d", i)))
+	}
+	_ = master.Delete("key0")
+	waitFor(t, func() bool {
+		_, err := slave.Get("key0")
+		return err == cbytecache.ErrNotFound
+	})
+	for i := 1; i < 200; i++ {
+		key := fmt.Sprintf("key%d", i)
+		me, err := master.GetEntryTo(nil, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var se cbytecache.Entry
+		waitFor(t, func() bool {
+			se, err = slave.GetEntryTo(nil, key)
+			return err == nil
+		})
+		if string(me.Body) != string(se.Body) || me.Expire != se.Expire {
+			t.Errorf("entry %s mismatch: need %s/%d got %s/%d", key, me.Body, me.Expire, se.Body, se.Expire)
+		}
+	}
+}
+
+func TestReplication(t *testing.T) {
+	t.Run("pipe", func(t *testing.T) {
+		testReplication(t, func(src *Source, rep *Replica) {
+			p := NewPipe(16)
+			go func() { _ = rep.Serve(p) }()
+			go func() { _ = src.AddPeer(p) }()
+		})
+	})
+	t.Run("tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = ln.Close() }()
+		testReplication(t, func(src *Source, rep *Replica) {
+			go func() { _ = src.Serve(ln) }()
+			conn, err := Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			go func() { _ = rep.Serve(conn) }()
+		})
+	})
+}
+
+func TestTransport(t *testing.T) {
+	t.Run("pipe drain", func(t *testing.T) {
+		p := NewPipe(4)
+		for i := 0; i < 3; i++ {
+			_ = p.Send(Message{Op: OpSet, Entry: cbytecache.Entry{Key: fmt.Sprintf("key%d", i)}})
+		}
+		_ = p.Close()
+		for i := 0; i < 3; i++ {
+			if m, err := p.Recv(); err != nil || m.Entry.Key != fmt.Sprintf("key%d", i) {
+				t.Fatalf("message %d mismatch: %s %v", i, m.Entry.Key, err)
+			}
+		}
+		if _, err := p.Recv(); err != ErrClosed {
+			t.Errorf("error mismatch: need ErrClosed got %v", err)
+		}
+	})
+	t.Run("frame too big", func(t *testing.T) {
+		c0, c1 := net.Pipe()
+		src, dst := NewConn(c0), NewConn(c1).SetMaxFrameSize(16)
+		defer func() { _ = src.Close(); _ = dst.Close() }()
+		go func() {
+			_ = src.Send(Message{Op: OpSet, Entry: cbytecache.Entry{Key: "foobar", Body: make([]byte, 32)}})
+			_ = src.Send(Message{Op: OpSet, Entry: cbytecache.Entry{Key: "foo", Body: []byte("bar")}})
+			_ = src.Flush()
+		}()
+		if _, err := dst.Recv(); err != ErrFrameTooBig {
+			t.Errorf("error mismatch: need ErrFrameTooBig got %v", err)
+		}
+		// Link keeps working after skipped frame.
+		if m, err := dst.Recv(); err != nil || m.Entry.Key != "foo" || string(m.Entry.Body) != "bar" {
+			t.Errorf("message mismatch: %+v %v", m, err)
+		}
+	})
+}
+
+func TestConnFrame(t *testing.T) {
+	c0, c1 := net.Pipe()
+	src, dst := NewConn(c0), NewConn(c1)
+	defer func() { _ = src.Close(); _ = dst.Close() }()
+	e := cbytecache.Entry{Key: "foo", Body: []byte("bar"), Expire: 42, Namespace: 7, Tags: []uint64{1, 2}}
+	go func() {
+		_ = src.Send(Message{Op: OpSet, Entry: e})
+		_ = src.Flush()
+	}()
+	m, err := dst.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := m.Entry
+	if m.Op != OpSet || r.Key != e.Key || string(r.Body) != string(e.Body) || r.Expire != e.Expire ||
+		r.Namespace != e.Namespace || len(r.Tags) != 2 || r.Tags[0] != 1 || r.Tags[1] != 2 {
+		t.Errorf("message mismatch: %+v", m)
+	}
+}
+
+func TestSkipFrame(t *testing.T) {
+	src := NewSource(0)
+	defer func() { _ = src.Close() }()
+	master := newCache(t, src)
+	if err := src.Bind(master); err != nil {
+		t.Fatal(err)
+	}
+	slave := newCache(t, nil)
+	rep, err := NewReplica(slave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = master.Set("big", make([]byte, 64))
+	_ = master.Set("foo", []byte("bar"))
+
+	c0, c1 := net.Pipe()
+	go func() { _ = rep.Serve(NewConn(c1)) }()
+	go func() { _ = src.AddPeer(NewConn(c0).SetMaxFrameSize(16)) }()
+	select {
+	case <-rep.Synced():
+	case <-time.After(5 * time.Second):
+		t.Fatal("initial sync timeout")
+	}
+	// Too big entry skips, but the link keeps working.
+	_ = master.Set("qwe", []byte("rty"))
+	waitFor(t, func() bool {
+		_, err := slave.Get("qwe")
+		return err == nil
+	})
+	if _, err = slave.Get("big"); err != cbytecache.ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+	if b, _ := slave.Get("foo"); string(b) != "bar" {
+		t.Errorf("body mismatch: need bar got %s", b)
+	}
+	if n := src.Skipped(); n != 1 {
+		t.Errorf("skipped mismatch: need 1 got %d", n)
+	}
+}
@@ -0,0 +1,227 @@
+package replication
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/koykov/cbytecache"
+)
+
+const defaultBuffer = 1024
+
+// Source is a replication source that ships cache changes to the peers.
+//
+// Source implements cbytecache.MutationStream, so it must be set to Config.MutationStream of the source cache and then
+// bound to the cache instance using Bind method:
+//
+//	src := replication.NewSource(0)
+//	conf.MutationStream = src
+//	cache, _ := cbytecache.New(conf)
+//	_ = src.Bind(cache)
+//
+// Each peer has own buffered queue. Full queue blocks cache writes until the peer catches up.
+type Source struct {
+	cache  *cbytecache.Cache
+	buffer int
+
+	mux    sync.RWMutex
+	peers  []*peer
+	closed bool
+	wg     sync.WaitGroup
+	// Count of skipped messages.
+	skip uint64
+}
+
+// Internal peer object.
+type peer struct {
+	t      Transport
+	q      chan Message
+	failed uint32
+	// Queue closed flag, guarded by source mutex.
+	closed bool
+}
+
+// NewSource makes new replication source with given size of peer queues.
+//
+// If buffer omit defaultBuffer (1024) will use instead.
+func NewSource(buffer int) *Source {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	s := Source{buffer: buffer}
+	return &s
+}
+
+// Bind binds source with cache instance. Cache uses to perform initial sync of new peers.
+func (s *Source) Bind(cache *cbytecache.Cache) error {
+	if cache == nil {
+		return ErrNoCache
+	}
+	s.mux.Lock()
+	s.cache = cache
+	s.mux.Unlock()
+	return nil
+}
+
+// Push sends cache mutation to all peers.
+func (s *Source) Push(m cbytecache.Mutation) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if len(s.peers) == 0 {
+		return nil
+	}
+	msg := messageOf(m)
+	if msg.Op == OpSet {
+		msg.Entry = msg.Entry.Copy()
+	} else {
+		msg.Entry = cbytecache.Entry{Key: string(msg.Entry.Key), Expire: msg.Entry.Expire, Namespace: msg.Entry.Namespace}
+	}
+	for _, p := range s.peers {
+		if atomic.LoadUint32(&p.failed) == 0 {
+			p.q <- msg
+		}
+	}
+	return nil
+}
+
+// AddPeer registers new peer and performs initial sync: all cache entries will send to the peer followed by OpSync
+// message. All changes made during the sync are delivered to the peer as well.
+func (s *Source) AddPeer(t Transport) error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return ErrClosed
+	}
+	if s.cache == nil {
+		s.mux.Unlock()
+		return ErrNoCache
+	}
+	p := &peer{t: t, q: make(chan Message, s.buffer)}
+	s.peers = append(s.peers, p)
+	cache := s.cache
+	s.wg.Add(1)
+	go s.work(p)
+	s.mux.Unlock()
+
+	return cache.DumpTo(peerWriter{s: s, p: p})
+}
+
+// Serve accepts incoming connections on the listener and registers each as a peer.
+//
+// Serve always returns non-nil error, e.g. after listener close.
+func (s *Source) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() { _ = s.AddPeer(NewConn(conn)) }()
+	}
+}
+
+// Peers returns count of active peers.
+func (s *Source) Peers() (n int) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, p := range s.peers {
+		if atomic.LoadUint32(&p.failed) == 0 {
+			n++
+		}
+	}
+	return
+}
+
+// Skipped returns count of messages skipped due to transport size limit (see Conn.SetMaxFrameSize).
+func (s *Source) Skipped() uint64 {
+	return atomic.LoadUint64(&s.skip)
+}
+
+// Close stops all peers and closes their transports.
+func (s *Source) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.closed = true
+	peers := s.peers
+	s.peers = nil
+	for _, p := range peers {
+		p.closed = true
+		close(p.q)
+	}
+	s.mux.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Deliver messages from peer queue to the transport.
+func (s *Source) work(p *peer) {
+	defer s.wg.Done()
+	defer func() { _ = p.t.Close() }()
+	for msg := range p.q {
+		if atomic.LoadUint32(&p.failed) == 1 {
+			// Drain the queue to unblock writers until peer removes.
+			continue
+		}
+		err := p.t.Send(msg)
+		if err == ErrFrameTooBig {
+			// Message exceeds transport limit, skip it instead of breaking the link.
+			atomic.AddUint64(&s.skip, 1)
+			err = nil
+		}
+		if err == nil && len(p.q) == 0 {
+			err = p.t.Flush()
+		}
+		if err != nil {
+			atomic.StoreUint32(&p.failed, 1)
+			go s.remove(p)
+		}
+	}
+}
+
+// Remove failed peer.
+func (s *Source) remove(p *peer) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i := range s.peers {
+		if s.peers[i] == p {
+			s.peers = append(s.peers[:i], s.peers[i+1:]...)
+			p.closed = true
+			close(p.q)
+			return
+		}
+	}
+}
+
+// Send message to the single peer.
+func (s *Source) send(p *peer, msg Message) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if p.closed || atomic.LoadUint32(&p.failed) == 1 {
+		return ErrClosed
+	}
+	p.q <- msg
+	return nil
+}
+
+// DumpWriter implementation to perform initial sync of the peer.
+type peerWriter struct {
+	s *Source
+	p *peer
+}
+
+func (w peerWriter) Write(e cbytecache.Entry) (int, error) {
+	if err := w.s.send(w.p, Message{Op: OpSet, Entry: e.Copy()}); err != nil {
+		return 0, err
+	}
+	return e.Size(), nil
+}
+
+func (w peerWriter) Flush() error {
+	return w.s.send(w.p, Message{Op: OpSync})
+}
@@ -0,0 +1,95 @@
+package replication
+
+import (
+	"errors"
+
+	"github.com/koykov/cbytecache"
+)
+
+// Op represents type of replication message.
+type Op uint8
+
+const (
+	// OpSet writes entry to the replica.
+	OpSet Op = iota
+	// OpDelete removes entry from the replica.
+	OpDelete
+	// OpExpire removes expired entry from the replica.
+	OpExpire
+	// OpSync indicates the end of initial sync.
+	OpSync
+)
+
+var (
+	ErrClosed  = errors.New("transport closed")
+	ErrBadOp   = errors.New("unknown replication op")
+	ErrNoCache = errors.New("no cache provided")
+)
+
+// Message represents single replication message.
+type Message struct {
+	Op    Op
+	Entry cbytecache.Entry
+}
+
+// Transport is the interface of one-way replication link between source and replica.
+//
+// Source side uses Send and Flush, replica side uses Recv. Both sides must Close the transport.
+type Transport interface {
+	// Send writes message to the link. Message data may be buffered until Flush call.
+	Send(m Message) error
+	// Flush delivers all buffered messages.
+	Flush() error
+	// Recv reads next message from the link. It blocks until message comes or link closes.
+	// Message data is valid until next Recv call.
+	Recv() (Message, error)
+	// Close closes the link.
+	Close() error
+}
+
+// String returns op name.
+func (op Op) String() string {
+	switch op {
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	case OpExpire:
+		return "expire"
+	case OpSync:
+		return "sync"
+	default:
+		return "unknown"
+	}
+}
+
+// Convert cache mutation to replication message.
+func messageOf(m cbytecache.Mutation) (msg Message) {
+	switch m.Type {
+	case cbytecache.MutationSet:
+		msg.Op = OpSet
+	case cbytecache.MutationDelete:
+		msg.Op = OpDelete
+	case cbytecache.MutationExpire:
+		msg.Op = OpExpire
+	}
+	msg.Entry = m.Entry
+	return
+}
+
+// Convert replication message to cache mutation.
+func mutationOf(msg Message) (m cbytecache.Mutation, err error) {
+	switch msg.Op {
+	case OpSet:
+		m.Type = cbytecache.MutationSet
+	case OpDelete:
+		m.Type = cbytecache.MutationDelete
+	case OpExpire:
+		m.Type = cbytecache.MutationExpire
+	default:
+		err = ErrBadOp
+		return
+	}
+	m.Entry = msg.Entry
+	return
+}
@@ -0,0 +1,581 @@
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/koykov/byteconv"
+	"github.com/koykov/cbytecache"
+)
+
+const (
+	// Max length of command line.
+	maxLineSize = 2048
+	// Max length of key allowed by memcached protocol.
+	maxKeySize = 250
+	// Exptime values greater than 30 days are unix timestamps.
+	maxRelExptime = 60 * 60 * 24 * 30
+	// Size of flags prefix, see WithFlags.
+	flagsSize = 4
+	// Default max item size, the same as memcached has.
+	defaultMaxItemSize = cbytecache.Megabyte
+)
+
+var (
+	ErrClosed    = errors.New("server closed")
+	ErrLineLimit = errors.New("line too long")
+
+	crlf = []byte("\r\n")
+)
+
+// Server serves the cache over TCP using memcached text protocol.
+//
+// Supported commands: get, gets, set, add, cas, delete, touch, stats, version and quit.
+// Entry version uses as CAS value (see cbytecache.Cache.SetIfVersion). Empty values aren't allowed by the cache and
+// cause SERVER_ERROR response.
+type Server struct {
+	cache *cbytecache.Cache
+	now   func() time.Time
+	flags bool
+	start time.Time
+	// Max size of data block.
+	maxItem uint64
+
+	mux    sync.Mutex
+	ln     map[net.Listener]struct{}
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+
+	st stats
+}
+
+// Server counters.
+type stats struct {
+	currConn, totalConn      int64
+	cmdGet, cmdSet, cmdTouch uint64
+	getHits, getMisses       uint64
+	deleteHits, deleteMisses uint64
+	touchHits, touchMisses   uint64
+}
+
+// NewServer makes new server over given cache.
+func NewServer(cache *cbytecache.Cache) (*Server, error) {
+	if cache == nil {
+		return nil, cbytecache.ErrBadCache
+	}
+	s := Server{
+		cache: cache,
+		now:   time.Now,
+		start: time.Now(),
+		ln:    make(map[net.Listener]struct{}),
+		conns: make(map[net.Conn]struct{}),
+
+		maxItem: uint64(defaultMaxItemSize),
+	}
+	if mes := uint64(cache.MaxEntrySize()); mes > 0 && mes < s.maxItem {
+		s.maxItem = mes
+	}
+	return &s, nil
+}
+
+// WithClock sets the clock to calculate entries expiration.
+//
+// Use the same clock as in cache config.
+func (s *Server) WithClock(clock cbytecache.Clock) *Server {
+	if clock != nil {
+		s.now = clock.Now
+	}
+	return s
+}
+
+// WithFlags enables storing of client flags. Flags store as 4 bytes prefix of entry body, so the cache must be used
+// only via the server. By default, flags are ignored and always returned as zero.
+func (s *Server) WithFlags(enable bool) *Server {
+	s.flags = enable
+	return s
+}
+
+// WithMaxItemSize limits size of data block in storage commands. Bigger items reject with SERVER_ERROR response.
+// By default, limit is 1MB or cache max entry size if it's less.
+func (s *Server) WithMaxItemSize(size cbytecache.MemorySize) *Server {
+	if size > 0 {
+		s.maxItem = uint64(size)
+	}
+	return s
+}
+
+// ListenAndServe listens TCP address and serves incoming connections.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts incoming connections on the listener and serves them.
+//
+// Serve always returns non-nil error, ErrClosed after Close call.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return ErrClosed
+	}
+	s.ln[ln] = struct{}{}
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(s.ln, ln)
+		s.mux.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mux.Lock()
+			closed := s.closed
+			s.mux.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		s.mux.Lock()
+		if s.closed {
+			s.mux.Unlock()
+			_ = conn.Close()
+			return ErrClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mux.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners and closes all active connections.
+func (s *Server) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.closed = true
+	for ln := range s.ln {
+		_ = ln.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mux.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Serve single connection.
+func (s *Server) serveConn(conn net.Conn) {
+	atomic.AddInt64(&s.st.currConn, 1)
+	atomic.AddInt64(&s.st.totalConn, 1)
+	defer func() {
+		atomic.AddInt64(&s.st.currConn, -1)
+		_ = conn.Close()
+		s.mux.Lock()
+		delete(s.conns, conn)
+		s.mux.Unlock()
+		s.wg.Done()
+	}()
+
+	c := session{
+		srv: s,
+		r:   bufio.NewReader(conn),
+		w:   bufio.NewWriter(conn),
+	}
+	for {
+		if err := c.handle(); err != nil {
+			_ = c.w.Flush()
+			return
+		}
+		// Flush responses only when all pipelined commands processed.
+		if c.r.Buffered() == 0 {
+			if err := c.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Connection session.
+type session struct {
+	srv  *Server
+	r    *bufio.Reader
+	w    *bufio.Writer
+	args [][]byte
+	key  []byte
+	buf  []byte
+	data []byte
+}
+
+// Read and process single command.
+func (c *session) handle() error {
+	line, err := c.readLine()
+	if err == ErrLineLimit {
+		c.w.WriteString("CLIENT_ERROR line too long\r\n")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	c.args = c.args[:0]
+	for _, f := range bytes.Fields(line) {
+		c.args = append(c.args, f)
+	}
+	if len(c.args) == 0 {
+		c.w.WriteString("ERROR\r\n")
+		return nil
+	}
+
+	switch string(c.args[0]) {
+	case "get":
+		return c.get(false)
+	case "gets":
+		return c.get(true)
+	case "set":
+		return c.store(false, false)
+	case "add":
+		return c.store(true, false)
+	case "cas":
+		return c.store(false, true)
+	case "delete":
+		return c.delete()
+	case "touch":
+		return c.touch()
+	case "stats":
+		return c.stats()
+	case "version":
+		c.w.WriteString("VERSION cbytecache\r\n")
+		return nil
+	case "quit":
+		return io.EOF
+	default:
+		c.w.WriteString("ERROR\r\n")
+		return nil
+	}
+}
+
+// get|gets <key>*
+func (c *session) get(cas bool) error {
+	if len(c.args) < 2 {
+		c.w.WriteString("ERROR\r\n")
+		return nil
+	}
+	for _, key := range c.args[1:] {
+		atomic.AddUint64(&c.srv.st.cmdGet, 1)
+		var (
+			ver uint64
+			err error
+		)
+		c.data, ver, err = c.srv.cache.GetToWithVersion(c.data[:0], byteconv.B2S(key))
+		if err != nil {
+			atomic.AddUint64(&c.srv.st.getMisses, 1)
+			// Missing mark means known miss.
+			if err != cbytecache.ErrNotFound && err != cbytecache.ErrMissing {
+				c.serverError(err)
+				return nil
+			}
+			continue
+		}
+		atomic.AddUint64(&c.srv.st.getHits, 1)
+		var flags uint32
+		body := c.data
+		if c.srv.flags && len(body) >= flagsSize {
+			flags = binary.LittleEndian.Uint32(body)
+			body = body[flagsSize:]
+		}
+		c.buf = append(c.buf[:0], "VALUE "...)
+		c.buf = append(c.buf, key...)
+		c.buf = append(c.buf, ' ')
+		c.buf = strconv.AppendUint(c.buf, uint64(flags), 10)
+		c.buf = append(c.buf, ' ')
+		c.buf = strconv.AppendInt(c.buf, int64(len(body)), 10)
+		if cas {
+			c.buf = append(c.buf, ' ')
+			c.buf = strconv.AppendUint(c.buf, ver, 10)
+		}
+		c.buf = append(c.buf, crlf...)
+		c.buf = append(c.buf, body...)
+		c.buf = append(c.buf, crlf...)
+		c.w.Write(c.buf)
+	}
+	c.w.WriteString("END\r\n")
+	return nil
+}
+
+// set|add <key> <flags> <exptime> <bytes> [noreply]
+// cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func (c *session) store(add, cas bool) error {
+	n := 5
+	if cas {
+		n++
+	}
+	if len(c.args) != n && len(c.args) != n+1 {
+		c.w.WriteString("ERROR\r\n")
+		return nil
+	}
+	noreply := len(c.args) == n+1 && string(c.args[n]) == "noreply"
+	var (
+		ver  uint64
+		err4 error
+	)
+	if cas {
+		ver, err4 = strconv.ParseUint(byteconv.B2S(c.args[5]), 10, 64)
+	}
+	// Copy the key since reading of data block overwrites the line.
+	c.key = append(c.key[:0], c.args[1]...)
+	key := c.key
+	flags, err1 := strconv.ParseUint(byteconv.B2S(c.args[2]), 10, 32)
+	exptime, err2 := strconv.ParseInt(byteconv.B2S(c.args[3]), 10, 64)
+	size, err3 := strconv.ParseUint(byteconv.B2S(c.args[4]), 10, 32)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || (cas && exptime < 0) || len(key) > maxKeySize {
+		c.w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+	atomic.AddUint64(&c.srv.st.cmdSet, 1)
+	if size > c.srv.maxItem {
+		// Skip data block to keep the stream in sync.
+		if _, err := io.CopyN(io.Discard, c.r, int64(size)+2); err != nil {
+			return err
+		}
+		if !noreply {
+			c.w.WriteString("SERVER_ERROR object too large for cache\r\n")
+		}
+		return nil
+	}
+
+	// Read data block.
+	c.data = c.data[:0]
+	if c.srv.flags {
+		c.data = append(c.data, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c.data, uint32(flags))
+	}
+	off, n := len(c.data), len(c.data)+int(size)+2
+	if cap(c.data) < n {
+		data := make([]byte, n)
+		copy(data, c.data)
+		c.data = data
+	}
+	c.data = c.data[:n]
+	if _, err := io.ReadFull(c.r, c.data[off:]); err != nil {
+		return err
+	}
+	if !bytes.Equal(c.data[len(c.data)-2:], crlf) {
+		c.w.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return nil
+	}
+	body := c.data[:len(c.data)-2]
+
+	var err error
+	if cas {
+		if ver == 0 {
+			// Zero CAS value never matches, check existence only to choose the response.
+			if _, err = c.srv.cache.GetEntryTo(c.buf[:0], byteconv.B2S(key)); err == nil {
+				err = cbytecache.ErrEntryVersion
+			}
+		} else {
+			err = c.srv.cache.SetEntryIfVersion(cbytecache.Entry{Key: byteconv.B2S(key), Body: body, Expire: c.expire(exptime)}, ver)
+		}
+	} else if exptime < 0 {
+		// Negative exptime means immediately expired item.
+		_ = c.srv.cache.Delete(byteconv.B2S(key))
+	} else if add {
+		// Zero version writes only if entry doesn't exist.
+		err = c.srv.cache.SetEntryIfVersion(cbytecache.Entry{Key: byteconv.B2S(key), Body: body, Expire: c.expire(exptime)}, 0)
+	} else {
+		err = c.srv.cache.SetEntry(cbytecache.Entry{Key: byteconv.B2S(key), Body: body, Expire: c.expire(exptime)})
+	}
+	if noreply {
+		return nil
+	}
+	switch err {
+	case nil, cbytecache.ErrEntryExpired:
+		c.w.WriteString("STORED\r\n")
+	case cbytecache.ErrEntryExists:
+		c.w.WriteString("NOT_STORED\r\n")
+	case cbytecache.ErrEntryVersion:
+		c.w.WriteString("EXISTS\r\n")
+	case cbytecache.ErrNotFound:
+		c.w.WriteString("NOT_FOUND\r\n")
+	default:
+		c.serverError(err)
+	}
+	return nil
+}
+
+// delete <key> [noreply]
+func (c *session) delete() error {
+	if len(c.args) != 2 && len(c.args) != 3 {
+		c.w.WriteString("ERROR\r\n")
+		return nil
+	}
+	noreply := len(c.args) == 3 && string(c.args[2]) == "noreply"
+	key := byteconv.B2S(c.args[1])
+	// Cache.Delete doesn't report missing entries, so check existence first.
+	_, err := c.srv.cache.GetEntryTo(c.buf[:0], key)
+	if err == nil {
+		err = c.srv.cache.Delete(key)
+	}
+	if err == nil {
+		atomic.AddUint64(&c.srv.st.deleteHits, 1)
+	} else if err == cbytecache.ErrNotFound || err == cbytecache.ErrMissing {
+		atomic.AddUint64(&c.srv.st.deleteMisses, 1)
+	}
+	if noreply {
+		return nil
+	}
+	switch err {
+	case nil:
+		c.w.WriteString("DELETED\r\n")
+	case cbytecache.ErrNotFound, cbytecache.ErrMissing:
+		c.w.WriteString("NOT_FOUND\r\n")
+	default:
+		c.serverError(err)
+	}
+	return nil
+}
+
+// touch <key> <exptime> [noreply]
+func (c *session) touch() error {
+	if len(c.args) != 3 && len(c.args) != 4 {
+		c.w.WriteString("ERROR\r\n")
+		return nil
+	}
+	noreply := len(c.args) == 4 && string(c.args[3]) == "noreply"
+	key := byteconv.B2S(c.args[1])
+	exptime, err := strconv.ParseInt(byteconv.B2S(c.args[2]), 10, 64)
+	if err != nil {
+		c.w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+	atomic.AddUint64(&c.srv.st.cmdTouch, 1)
+	for {
+		var e cbytecache.Entry
+		if e, err = c.srv.cache.GetEntryTo(c.data[:0], key); err != nil {
+			break
+		}
+		c.data = e.Body
+		if exptime < 0 {
+			err = c.srv.cache.Delete(key)
+			break
+		}
+		// Versioned write doesn't resurrect concurrently deleted entry and doesn't overwrite concurrent set. The last
+		// case retries touch over the new entry.
+		e.Expire = c.expire(exptime)
+		if err = c.srv.cache.SetEntryIfVersion(e, e.Version); err == cbytecache.ErrEntryExpired {
+			// Touch to the past expires the entry.
+			err = c.srv.cache.Delete(key)
+		}
+		if err != cbytecache.ErrEntryVersion {
+			break
+		}
+	}
+	if err == nil {
+		atomic.AddUint64(&c.srv.st.touchHits, 1)
+	} else if err == cbytecache.ErrNotFound || err == cbytecache.ErrMissing {
+		atomic.AddUint64(&c.srv.st.touchMisses, 1)
+	}
+	if noreply {
+		return nil
+	}
+	switch err {
+	case nil:
+		c.w.WriteString("TOUCHED\r\n")
+	case cbytecache.ErrNotFound, cbytecache.ErrMissing:
+		c.w.WriteString("NOT_FOUND\r\n")
+	default:
+		c.serverError(err)
+	}
+	return nil
+}
+
+// stats
+func (c *session) stats() error {
+	s := c.srv
+	cs := s.cache.Stats()
+	now := s.now()
+	c.stat("pid", int64(os.Getpid()))
+	c.stat("uptime", int64(now.Sub(s.start)/time.Second))
+	c.stat("time", now.Unix())
+	c.statu("curr_connections", uint64(atomic.LoadInt64(&s.st.currConn)))
+	c.statu("total_connections", uint64(atomic.LoadInt64(&s.st.totalConn)))
+	c.statu("cmd_get", atomic.LoadUint64(&s.st.cmdGet))
+	c.statu("cmd_set", atomic.LoadUint64(&s.st.cmdSet))
+	c.statu("cmd_touch", atomic.LoadUint64(&s.st.cmdTouch))
+	c.statu("get_hits", atomic.LoadUint64(&s.st.getHits))
+	c.statu("get_misses", atomic.LoadUint64(&s.st.getMisses))
+	c.statu("delete_hits", atomic.LoadUint64(&s.st.deleteHits))
+	c.statu("delete_misses", atomic.LoadUint64(&s.st.deleteMisses))
+	c.statu("touch_hits", atomic.LoadUint64(&s.st.touchHits))
+	c.statu("touch_misses", atomic.LoadUint64(&s.st.touchMisses))
+	c.statu("curr_items", cs.Entries)
+	c.statu("bytes", uint64(cs.Size.Used()))
+	c.statu("limit_maxbytes", uint64(cs.Size.Total()))
+	c.w.WriteString("END\r\n")
+	return nil
+}
+
+func (c *session) stat(name string, val int64) {
+	c.buf = append(c.buf[:0], "STAT "...)
+	c.buf = append(c.buf, name...)
+	c.buf = append(c.buf, ' ')
+	c.buf = strconv.AppendInt(c.buf, val, 10)
+	c.buf = append(c.buf, crlf...)
+	c.w.Write(c.buf)
+}
+
+func (c *session) statu(name string, val uint64) {
+	c.buf = append(c.buf[:0], "STAT "...)
+	c.buf = append(c.buf, name...)
+	c.buf = append(c.buf, ' ')
+	c.buf = strconv.AppendUint(c.buf, val, 10)
+	c.buf = append(c.buf, crlf...)
+	c.w.Write(c.buf)
+}
+
+func (c *session) serverError(err error) {
+	c.w.WriteString("SERVER_ERROR ")
+	c.w.WriteString(err.Error())
+	c.w.WriteString("\r\n")
+}
+
+// Convert memcached exptime to absolute expire timestamp. Zero means default expiration of the cache.
+func (c *session) expire(exptime int64) uint32 {
+	if exptime == 0 {
+		return 0
+	}
+	if exptime > maxRelExptime {
+		return uint32(exptime)
+	}
+	return uint32(c.srv.now().Unix() + exptime)
+}
+
+// Read command line without trailing CRLF.
+func (c *session) readLine() ([]byte, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || (err == nil && len(line) > maxLineSize) {
+		return nil, ErrLineLimit
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
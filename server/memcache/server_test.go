@@ -0,0 +1,142 @@
+package memcache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+// Minimal text protocol client.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) send(t *testing.T, cmd string) {
+	if _, err := c.conn.Write([]byte(cmd)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *client) line(t *testing.T) string {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+func (c *client) expect(t *testing.T, cmd string, resp ...string) {
+	t.Helper()
+	c.send(t, cmd)
+	for _, r := range resp {
+		if line := c.line(t); line != r {
+			t.Errorf("%q: need %q got %q", strings.TrimSpace(cmd), r, line)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	srv, err := NewServer(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.WithFlags(true)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- srv.Serve(ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	c := client{conn: conn, r: bufio.NewReader(conn)}
+
+	c.expect(t, "set foo 42 0 3\r\nbar\r\n", "STORED")
+	c.expect(t, "get foo\r\n", "VALUE foo 42 3", "bar", "END")
+	// CAS unique is the entry version.
+	_, cas, _ := cache.GetWithVersion("foo")
+	c.expect(t, "gets foo missing\r\n", fmt.Sprintf("VALUE foo 42 3 %d", cas), "bar", "END")
+	c.expect(t, fmt.Sprintf("cas foo 7 0 3 %d\r\nnew\r\n", cas), "STORED")
+	c.expect(t, fmt.Sprintf("cas foo 7 0 3 %d\r\nold\r\n", cas), "EXISTS")
+	c.expect(t, "cas missing 0 0 1 1\r\na\r\n", "NOT_FOUND")
+	_, cas1, _ := cache.GetWithVersion("foo")
+	if cas1 <= cas {
+		t.Errorf("cas unique must grow: %d <= %d", cas1, cas)
+	}
+	c.expect(t, "gets foo\r\n", fmt.Sprintf("VALUE foo 7 3 %d", cas1), "new", "END")
+	c.expect(t, "add foo 0 0 3\r\nqux\r\n", "NOT_STORED")
+	c.expect(t, "add baz 0 60 3\r\nqux\r\n", "STORED")
+	c.expect(t, "set foo 1 0 4\r\nbar1\r\n", "STORED")
+	c.expect(t, "get foo baz\r\n", "VALUE foo 1 4", "bar1", "VALUE baz 0 3", "qux", "END")
+	c.expect(t, "touch baz 120\r\n", "TOUCHED")
+	c.expect(t, "touch missing 120\r\n", "NOT_FOUND")
+	if e, _ := cache.GetEntryTo(nil, "baz"); e.Expire < uint32(time.Now().Unix()+100) || string(e.Body) != "\x00\x00\x00\x00qux" {
+		t.Errorf("touch mismatch: expire %d body %q", e.Expire, e.Body)
+	}
+	c.expect(t, "add baz 0 60 3\r\nnew\r\n", "NOT_STORED")
+	// Missing marks are misses and don't break multi-get.
+	_ = cache.SetMissing("gone", time.Minute)
+	c.expect(t, "get gone baz\r\n", "VALUE baz 0 3", "qux", "END")
+	c.expect(t, "touch gone 120\r\n", "NOT_FOUND")
+	c.expect(t, "delete foo\r\n", "DELETED")
+	c.expect(t, "delete foo\r\n", "NOT_FOUND")
+	c.expect(t, "get foo\r\n", "END")
+	c.expect(t, "set x 0 0 1 noreply\r\na\r\nget x\r\n", "VALUE x 0 1", "a", "END")
+	// Rest of the bad data chunk is treated as the next command line.
+	c.expect(t, "set x 0 0 1\r\nabc\r\n", "CLIENT_ERROR bad data chunk", "ERROR")
+	c.expect(t, "unknown\r\n", "ERROR")
+	// Too large item skips with its data block.
+	c.expect(t, "set big 0 0 2000000\r\n"+strings.Repeat("a", 2000000)+"\r\nget big\r\n",
+		"SERVER_ERROR object too large for cache", "END")
+
+	// Pipelined commands.
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, "set key%d 0 0 1\r\n%d\r\n", i, i)
+	}
+	resp := make([]string, 10)
+	for i := range resp {
+		resp[i] = "STORED"
+	}
+	c.expect(t, b.String(), resp...)
+
+	c.send(t, "stats\r\n")
+	stats := make(map[string]string)
+	for {
+		line := c.line(t)
+		if line == "END" {
+			break
+		}
+		parts := strings.SplitN(line, " ", 3)
+		stats[parts[1]] = parts[2]
+	}
+	if stats["curr_items"] != "12" {
+		t.Errorf("curr_items mismatch: need 12 got %s", stats["curr_items"])
+	}
+	if stats["get_hits"] == "0" || stats["delete_hits"] != "1" {
+		t.Errorf("stats mismatch: %v", stats)
+	}
+
+	c.expect(t, "quit\r\n")
+	_ = srv.Close()
+	if err = <-done; err != ErrClosed {
+		t.Errorf("serve must return ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,745 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/koykov/byteconv"
+	"github.com/koykov/cbytecache"
+)
+
+const (
+	// Max length of command line or bulk header.
+	maxLineSize = 64 * 1024
+	// Max size of single bulk string if cache has no max entry size.
+	maxBulkSize = 512 * 1024 * 1024
+	// Bulk strings read by chunks of that size.
+	bulkChunkSize = 64 * 1024
+	// Max count of command arguments.
+	maxArgs = 1024 * 1024
+	// Default count of keys returned by SCAN.
+	defaultScanCount = 10
+)
+
+var (
+	ErrClosed   = errors.New("server closed")
+	ErrProtocol = errors.New("protocol error")
+
+	crlf = []byte("\r\n")
+)
+
+// Server serves the cache over TCP using RESP2 (Redis serialization protocol).
+//
+// Supported commands: GET, SET (with EX/PX and NX/XX options), DEL, EXISTS, TTL, PTTL, SCAN, INFO, FLUSHALL, PING and
+// QUIT. Both multi-bulk and inline commands are supported, pipelined commands are processed in order and responses are
+// flushed in one write.
+type Server struct {
+	cache *cbytecache.Cache
+	now   func() time.Time
+	start time.Time
+	// Max size of single bulk string.
+	maxBulk int
+
+	mux    sync.Mutex
+	ln     map[net.Listener]struct{}
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+
+	st stats
+}
+
+// Server counters.
+type stats struct {
+	currConn, totalConn int64
+	commands            uint64
+	hits, misses        uint64
+}
+
+// NewServer makes new server over given cache.
+func NewServer(cache *cbytecache.Cache) (*Server, error) {
+	if cache == nil {
+		return nil, cbytecache.ErrBadCache
+	}
+	s := Server{
+		cache: cache,
+		now:   time.Now,
+		start: time.Now(),
+		ln:    make(map[net.Listener]struct{}),
+		conns: make(map[net.Conn]struct{}),
+
+		maxBulk: maxBulkSize,
+	}
+	if mes := int(cache.MaxEntrySize()); mes > 0 && mes < s.maxBulk {
+		// Bulk string bigger than max entry size can't be stored anyway.
+		s.maxBulk = mes
+	}
+	return &s, nil
+}
+
+// WithClock sets the clock to calculate entries expiration and TTL.
+//
+// Use the same clock as in cache config.
+func (s *Server) WithClock(clock cbytecache.Clock) *Server {
+	if clock != nil {
+		s.now = clock.Now
+	}
+	return s
+}
+
+// ListenAndServe listens TCP address and serves incoming connections.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts incoming connections on the listener and serves them.
+//
+// Serve always returns non-nil error, ErrClosed after Close call.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return ErrClosed
+	}
+	s.ln[ln] = struct{}{}
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(s.ln, ln)
+		s.mux.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mux.Lock()
+			closed := s.closed
+			s.mux.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		s.mux.Lock()
+		if s.closed {
+			s.mux.Unlock()
+			_ = conn.Close()
+			return ErrClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mux.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners and closes all active connections.
+func (s *Server) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.closed = true
+	for ln := range s.ln {
+		_ = ln.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mux.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Serve single connection.
+func (s *Server) serveConn(conn net.Conn) {
+	atomic.AddInt64(&s.st.currConn, 1)
+	atomic.AddInt64(&s.st.totalConn, 1)
+	defer func() {
+		atomic.AddInt64(&s.st.currConn, -1)
+		_ = conn.Close()
+		s.mux.Lock()
+		delete(s.conns, conn)
+		s.mux.Unlock()
+		s.wg.Done()
+	}()
+
+	c := session{
+		srv: s,
+		r:   bufio.NewReader(conn),
+		w:   bufio.NewWriter(conn),
+
+		scan: scanner{bkt: -1},
+	}
+	for {
+		err := c.read()
+		if err == ErrProtocol {
+			c.error("Protocol error")
+			_ = c.w.Flush()
+			return
+		}
+		if err != nil {
+			return
+		}
+		if len(c.args) > 0 {
+			atomic.AddUint64(&s.st.commands, 1)
+			if err = c.exec(); err != nil {
+				_ = c.w.Flush()
+				return
+			}
+		}
+		// Flush responses only when all pipelined commands processed.
+		if c.r.Buffered() == 0 {
+			if err = c.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Connection session.
+//
+// All buffers are reused between commands.
+type session struct {
+	srv *Server
+	r   *bufio.Reader
+	w   *bufio.Writer
+
+	// Command arguments point to buf.
+	args [][]byte
+	offs []int
+	buf  []byte
+	// Response buffer.
+	rbuf []byte
+	// Entry buffer.
+	data []byte
+	// Keys snapshot for SCAN.
+	scan scanner
+}
+
+// Read single command.
+func (c *session) read() error {
+	c.buf, c.offs, c.args = c.buf[:0], c.offs[:0], c.args[:0]
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if len(line) == 0 {
+		return nil
+	}
+	if line[0] != '*' {
+		// Inline command.
+		for _, f := range bytes.Fields(line) {
+			c.buf = append(c.buf, f...)
+			c.offs = append(c.offs, len(c.buf))
+		}
+		c.split()
+		return nil
+	}
+
+	n, err := strconv.Atoi(byteconv.B2S(line[1:]))
+	if err != nil || n > maxArgs {
+		return ErrProtocol
+	}
+	for i := 0; i < n; i++ {
+		if line, err = c.readLine(); err != nil {
+			return err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return ErrProtocol
+		}
+		l, err := strconv.Atoi(byteconv.B2S(line[1:]))
+		if err != nil || l < 0 || l > c.srv.maxBulk {
+			return ErrProtocol
+		}
+		off := len(c.buf)
+		// Grow the buffer as data arrives, so declared length doesn't allocate memory upfront.
+		for rest := l + 2; rest > 0; {
+			n := rest
+			if n > bulkChunkSize {
+				n = bulkChunkSize
+			}
+			lo := len(c.buf)
+			c.buf = append(c.buf, make([]byte, n)...)
+			if _, err = io.ReadFull(c.r, c.buf[lo:]); err != nil {
+				return err
+			}
+			rest -= n
+		}
+		if !bytes.Equal(c.buf[off+l:], crlf) {
+			return ErrProtocol
+		}
+		c.buf = c.buf[:off+l]
+		c.offs = append(c.offs, len(c.buf))
+	}
+	c.split()
+	return nil
+}
+
+// Make arguments from offsets.
+func (c *session) split() {
+	var lo int
+	for _, hi := range c.offs {
+		c.args = append(c.args, c.buf[lo:hi:hi])
+		lo = hi
+	}
+}
+
+// Execute command.
+func (c *session) exec() error {
+	cmd := c.args[0]
+	for i := range cmd {
+		if cmd[i] >= 'A' && cmd[i] <= 'Z' {
+			cmd[i] += 'a' - 'A'
+		}
+	}
+	switch string(cmd) {
+	case "get":
+		c.get()
+	case "set":
+		c.set()
+	case "del":
+		c.del()
+	case "exists":
+		c.exists()
+	case "ttl":
+		c.ttl(time.Second)
+	case "pttl":
+		c.ttl(time.Millisecond)
+	case "scan":
+		c.scanCmd()
+	case "info":
+		c.info()
+	case "flushall":
+		c.flushall()
+	case "ping":
+		if len(c.args) > 1 {
+			c.bulk(c.args[1])
+		} else {
+			c.simple("PONG")
+		}
+	case "quit":
+		c.simple("OK")
+		return io.EOF
+	default:
+		c.rbuf = append(c.rbuf[:0], "-ERR unknown command '"...)
+		c.rbuf = append(c.rbuf, cmd...)
+		c.rbuf = append(c.rbuf, "'\r\n"...)
+		c.w.Write(c.rbuf)
+	}
+	return nil
+}
+
+// GET key
+func (c *session) get() {
+	if len(c.args) != 2 {
+		c.arity()
+		return
+	}
+	var err error
+	if c.data, err = c.srv.cache.GetTo(c.data[:0], byteconv.B2S(c.args[1])); err != nil {
+		if err == cbytecache.ErrNotFound {
+			atomic.AddUint64(&c.srv.st.misses, 1)
+			c.null()
+			return
+		}
+		c.cacheError(err)
+		return
+	}
+	atomic.AddUint64(&c.srv.st.hits, 1)
+	c.bulk(c.data)
+}
+
+// SET key value [EX seconds|PX milliseconds] [NX|XX]
+func (c *session) set() {
+	if len(c.args) < 3 {
+		c.arity()
+		return
+	}
+	key, val := byteconv.B2S(c.args[1]), c.args[2]
+	var (
+		ttl    time.Duration
+		nx, xx bool
+	)
+	for i := 3; i < len(c.args); i++ {
+		opt := c.args[i]
+		switch {
+		case bytes.EqualFold(opt, []byte("nx")):
+			nx = true
+		case bytes.EqualFold(opt, []byte("xx")):
+			xx = true
+		case bytes.EqualFold(opt, []byte("ex")) || bytes.EqualFold(opt, []byte("px")):
+			if i+1 >= len(c.args) || ttl != 0 {
+				c.error("ERR syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(byteconv.B2S(c.args[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				c.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = time.Duration(n) * time.Second
+			if opt[0] == 'p' || opt[0] == 'P' {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		default:
+			c.error("ERR syntax error")
+			return
+		}
+	}
+	if nx && xx {
+		c.error("ERR syntax error")
+		return
+	}
+
+	var expire uint32
+	if ttl > 0 {
+		expire = uint32(c.srv.now().Add(ttl).Unix())
+		if ttl%time.Second != 0 {
+			// Cache has seconds precision, so round up sub-second remainder.
+			expire++
+		}
+	}
+	e := cbytecache.Entry{Key: key, Body: val, Expire: expire}
+	var err error
+	switch {
+	case nx:
+		// Zero version writes only if entry doesn't exist.
+		err = c.srv.cache.SetEntryIfVersion(e, 0)
+	case xx:
+		err = c.setXX(e)
+	default:
+		err = c.srv.cache.SetEntry(e)
+	}
+	switch err {
+	case nil:
+		c.simple("OK")
+	case cbytecache.ErrEntryExists, cbytecache.ErrNotFound:
+		// Condition not met.
+		c.null()
+	default:
+		c.cacheError(err)
+	}
+}
+
+// Overwrite existing entry. Versioned write doesn't resurrect concurrently deleted entry, concurrent overwrite
+// retries the write over the new entry.
+func (c *session) setXX(e cbytecache.Entry) error {
+	for {
+		cur, err := c.srv.cache.GetEntryTo(c.data[:0], e.Key)
+		if err == cbytecache.ErrMissing {
+			err = cbytecache.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c.data = cur.Body
+		if err = c.srv.cache.SetEntryIfVersion(e, cur.Version); err != cbytecache.ErrEntryVersion {
+			return err
+		}
+	}
+}
+
+// DEL key [key ...]
+func (c *session) del() {
+	if len(c.args) < 2 {
+		c.arity()
+		return
+	}
+	var n int64
+	for _, key := range c.args[1:] {
+		k := byteconv.B2S(key)
+		// Cache.Delete doesn't report missing entries, so check existence first.
+		if e, err := c.srv.cache.GetEntryTo(c.data[:0], k); err == nil {
+			c.data = e.Body
+			if c.srv.cache.Delete(k) == nil {
+				n++
+			}
+		}
+	}
+	c.integer(n)
+}
+
+// EXISTS key [key ...]
+func (c *session) exists() {
+	if len(c.args) < 2 {
+		c.arity()
+		return
+	}
+	var n int64
+	for _, key := range c.args[1:] {
+		if e, err := c.srv.cache.GetEntryTo(c.data[:0], byteconv.B2S(key)); err == nil {
+			c.data = e.Body
+			n++
+		}
+	}
+	c.integer(n)
+}
+
+// TTL|PTTL key
+func (c *session) ttl(unit time.Duration) {
+	if len(c.args) != 2 {
+		c.arity()
+		return
+	}
+	e, err := c.srv.cache.GetEntryTo(c.data[:0], byteconv.B2S(c.args[1]))
+	if err == cbytecache.ErrNotFound {
+		c.integer(-2)
+		return
+	}
+	if err != nil {
+		c.cacheError(err)
+		return
+	}
+	c.data = e.Body
+	ttl := time.Unix(int64(e.Expire), 0).Sub(c.srv.now())
+	if ttl < 0 {
+		ttl = 0
+	}
+	c.integer(int64((ttl + unit/2) / unit))
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count]
+func (c *session) scanCmd() {
+	if len(c.args) < 2 {
+		c.arity()
+		return
+	}
+	cursor, err := strconv.ParseUint(byteconv.B2S(c.args[1]), 10, 64)
+	if err != nil {
+		c.error("ERR invalid cursor")
+		return
+	}
+	var match []byte
+	count := defaultScanCount
+	for i := 2; i < len(c.args); i += 2 {
+		if i+1 >= len(c.args) {
+			c.error("ERR syntax error")
+			return
+		}
+		switch {
+		case bytes.EqualFold(c.args[i], []byte("match")):
+			match = c.args[i+1]
+		case bytes.EqualFold(c.args[i], []byte("count")):
+			if count, err = strconv.Atoi(byteconv.B2S(c.args[i+1])); err != nil || count <= 0 {
+				c.error("ERR syntax error")
+				return
+			}
+		default:
+			c.error("ERR syntax error")
+			return
+		}
+	}
+	keys, next, err := c.scan.page(c.srv.cache, cursor, count)
+	if err != nil {
+		c.cacheError(err)
+		return
+	}
+
+	c.rbuf = append(c.rbuf[:0], "*2\r\n"...)
+	c.w.Write(c.rbuf)
+	c.bulk(strconv.AppendUint(c.data[:0], next, 10))
+	var n int
+	c.scan.match = c.scan.match[:0]
+	for _, k := range keys {
+		if len(match) > 0 {
+			if ok, _ := path.Match(byteconv.B2S(match), k); !ok {
+				continue
+			}
+		}
+		c.scan.match = append(c.scan.match, k)
+		n++
+	}
+	c.array(n)
+	for _, k := range c.scan.match {
+		c.bulk(byteconv.S2B(k))
+	}
+}
+
+// INFO [section]
+func (c *session) info() {
+	s := c.srv
+	cs := s.cache.Stats()
+	b := c.data[:0]
+	b = append(b, "# Server\r\n"...)
+	b = appendField(b, "uptime_in_seconds", int64(s.now().Sub(s.start)/time.Second))
+	b = append(b, "\r\n# Clients\r\n"...)
+	b = appendField(b, "connected_clients", atomic.LoadInt64(&s.st.currConn))
+	b = append(b, "\r\n# Memory\r\n"...)
+	b = appendField(b, "used_memory", int64(cs.Size.Used()))
+	b = appendField(b, "maxmemory", int64(cs.Size.Total()))
+	b = append(b, "\r\n# Stats\r\n"...)
+	b = appendField(b, "total_connections_received", atomic.LoadInt64(&s.st.totalConn))
+	b = appendField(b, "total_commands_processed", int64(atomic.LoadUint64(&s.st.commands)))
+	b = appendField(b, "keyspace_hits", int64(atomic.LoadUint64(&s.st.hits)))
+	b = appendField(b, "keyspace_misses", int64(atomic.LoadUint64(&s.st.misses)))
+	b = append(b, "\r\n# Keyspace\r\n"...)
+	b = append(b, "db0:keys="...)
+	b = strconv.AppendUint(b, cs.Entries, 10)
+	b = append(b, "\r\n"...)
+	c.data = b
+	c.bulk(c.data)
+}
+
+// FLUSHALL [ASYNC|SYNC]
+func (c *session) flushall() {
+	if err := c.srv.cache.Reset(); err != nil {
+		c.cacheError(err)
+		return
+	}
+	c.simple("OK")
+}
+
+func (c *session) simple(s string) {
+	c.rbuf = append(c.rbuf[:0], '+')
+	c.rbuf = append(c.rbuf, s...)
+	c.rbuf = append(c.rbuf, crlf...)
+	c.w.Write(c.rbuf)
+}
+
+func (c *session) error(s string) {
+	c.rbuf = append(c.rbuf[:0], '-')
+	c.rbuf = append(c.rbuf, s...)
+	c.rbuf = append(c.rbuf, crlf...)
+	c.w.Write(c.rbuf)
+}
+
+func (c *session) cacheError(err error) {
+	c.rbuf = append(c.rbuf[:0], "-ERR "...)
+	c.rbuf = append(c.rbuf, err.Error()...)
+	c.rbuf = append(c.rbuf, crlf...)
+	c.w.Write(c.rbuf)
+}
+
+func (c *session) arity() {
+	c.rbuf = append(c.rbuf[:0], "-ERR wrong number of arguments for '"...)
+	c.rbuf = append(c.rbuf, c.args[0]...)
+	c.rbuf = append(c.rbuf, "' command\r\n"...)
+	c.w.Write(c.rbuf)
+}
+
+func (c *session) integer(n int64) {
+	c.rbuf = append(c.rbuf[:0], ':')
+	c.rbuf = strconv.AppendInt(c.rbuf, n, 10)
+	c.rbuf = append(c.rbuf, crlf...)
+	c.w.Write(c.rbuf)
+}
+
+func (c *session) bulk(p []byte) {
+	c.rbuf = append(c.rbuf[:0], '$')
+	c.rbuf = strconv.AppendInt(c.rbuf, int64(len(p)), 10)
+	c.rbuf = append(c.rbuf, crlf...)
+	c.w.Write(c.rbuf)
+	c.w.Write(p)
+	c.w.Write(crlf)
+}
+
+func (c *session) null() {
+	c.w.WriteString("$-1\r\n")
+}
+
+func (c *session) array(n int) {
+	c.rbuf = append(c.rbuf[:0], '*')
+	c.rbuf = strconv.AppendInt(c.rbuf, int64(n), 10)
+	c.rbuf = append(c.rbuf, crlf...)
+	c.w.Write(c.rbuf)
+}
+
+// Read line without trailing CRLF.
+func (c *session) readLine() ([]byte, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || (err == nil && len(line) > maxLineSize) {
+		return nil, ErrProtocol
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+func appendField(b []byte, name string, val int64) []byte {
+	b = append(b, name...)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, val, 10)
+	return append(b, crlf...)
+}
+
+// Keys iterator of SCAN command.
+//
+// Cache has no stable key order, so iteration walks over buckets and pages over sorted keys snapshot of the current
+// bucket. Cursor contains bucket index in high 32 bits and position in bucket keys in low bits. Keys written to
+// already scanned buckets will not return until new iteration.
+type scanner struct {
+	buf   []byte
+	offs  []int
+	keys  []string
+	match []string
+	// Index of bucket which keys are in snapshot, -1 if snapshot is empty.
+	bkt int64
+	// Index of next bucket.
+	next uint
+}
+
+// Get page of at most count keys starting from cursor and next cursor (zero at the end of iteration).
+func (s *scanner) page(cache *cbytecache.Cache, cursor uint64, count int) ([]string, uint64, error) {
+	bkt, pos := uint(cursor>>32), int(cursor&math.MaxUint32)
+	if pos == 0 || int64(bkt) != s.bkt {
+		// New bucket or new iteration, make new snapshot.
+		if err := s.snapshot(cache, bkt); err != nil {
+			return nil, 0, err
+		}
+	}
+	// Skip empty buckets.
+	for pos >= len(s.keys) {
+		if s.next == 0 {
+			return nil, 0, nil
+		}
+		bkt, pos = s.next, 0
+		if err := s.snapshot(cache, bkt); err != nil {
+			return nil, 0, err
+		}
+	}
+	hi := pos + count
+	if hi < len(s.keys) {
+		return s.keys[pos:hi], uint64(bkt)<<32 | uint64(hi), nil
+	}
+	return s.keys[pos:], uint64(s.next) << 32, nil
+}
+
+// Collect keys of bucket with index bkt.
+func (s *scanner) snapshot(cache *cbytecache.Cache, bkt uint) (err error) {
+	s.buf, s.offs, s.keys = s.buf[:0], s.offs[:0], s.keys[:0]
+	s.bkt = -1
+	if s.next, err = cache.ScanKeys(bkt, s.add); err != nil {
+		return
+	}
+	s.bkt = int64(bkt)
+	var lo int
+	for _, hi := range s.offs {
+		s.keys = append(s.keys, byteconv.B2S(s.buf[lo:hi]))
+		lo = hi
+	}
+	sort.Strings(s.keys)
+	return
+}
+
+func (s *scanner) add(key string) {
+	s.buf = append(s.buf, key...)
+	s.offs = append(s.offs, len(s.buf))
+}
@@ -0,0 +1,183 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+// Minimal RESP client.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) send(t *testing.T, args ...string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Read reply and flatten it to string.
+func (c *client) reply(t *testing.T) string {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch line[0] {
+	case '$':
+		var n int
+		_, _ = fmt.Sscanf(line[1:], "%d", &n)
+		if n < 0 {
+			return "(nil)"
+		}
+		buf := make([]byte, n+2)
+		if _, err = c.r.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	case '*':
+		var n int
+		_, _ = fmt.Sscanf(line[1:], "%d", &n)
+		items := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			items = append(items, c.reply(t))
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	default:
+		return line
+	}
+}
+
+func (c *client) expect(t *testing.T, resp string, args ...string) {
+	t.Helper()
+	c.send(t, args...)
+	if r := c.reply(t); r != resp {
+		t.Errorf("%v: need %q got %q", args, resp, r)
+	}
+}
+
+func TestServer(t *testing.T) {
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	srv, err := NewServer(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- srv.Serve(ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	c := client{conn: conn, r: bufio.NewReader(conn)}
+
+	c.expect(t, "+PONG", "PING")
+	c.expect(t, "+OK", "SET", "foo", "bar")
+	c.expect(t, "bar", "GET", "foo")
+	c.expect(t, "(nil)", "GET", "missing")
+	c.expect(t, "+OK", "set", "foo", "baz", "EX", "100")
+	c.expect(t, "baz", "get", "foo")
+	// Cache has seconds precision of expiration.
+	c.send(t, "TTL", "foo")
+	if r := c.reply(t); r != ":100" && r != ":99" {
+		t.Errorf("TTL mismatch: need :100 got %q", r)
+	}
+	c.expect(t, ":-2", "TTL", "missing")
+	c.expect(t, "(nil)", "SET", "foo", "qux", "NX")
+	c.expect(t, "(nil)", "SET", "missing", "qux", "XX")
+	c.expect(t, "baz", "GET", "foo")
+	c.expect(t, "+OK", "SET", "nx", "1", "NX")
+	c.expect(t, "1", "GET", "nx")
+	c.expect(t, "+OK", "SET", "nx", "2", "XX", "EX", "200")
+	c.expect(t, "2", "GET", "nx")
+	c.send(t, "TTL", "nx")
+	if r := c.reply(t); r != ":200" && r != ":199" {
+		t.Errorf("TTL mismatch: need :200 got %q", r)
+	}
+	c.expect(t, ":1", "DEL", "nx")
+	// Missing mark doesn't satisfy XX.
+	_ = cache.SetMissing("gone", time.Minute)
+	c.expect(t, "(nil)", "SET", "gone", "qux", "XX")
+	c.expect(t, "+OK", "SET", "bar", "1", "PX", "1500")
+	c.expect(t, ":2", "EXISTS", "foo", "bar", "missing")
+	c.expect(t, ":1", "DEL", "bar", "missing")
+	c.expect(t, ":1", "EXISTS", "foo", "bar")
+	c.expect(t, "-ERR wrong number of arguments for 'get' command", "GET")
+	c.expect(t, "-ERR syntax error", "SET", "foo", "bar", "EX")
+	c.expect(t, "-ERR unknown command 'hset'", "HSET", "foo", "bar")
+
+	// Inline command.
+	_, _ = conn.Write([]byte("GET foo\r\n"))
+	if r := c.reply(t); r != "baz" {
+		t.Errorf("inline: need %q got %q", "baz", r)
+	}
+
+	// Pipelined commands.
+	var b strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&b, "*3\r\n$3\r\nSET\r\n$%d\r\nkey%d\r\n$1\r\n%d\r\n", len(fmt.Sprintf("key%d", i)), i, i%10)
+	}
+	_, _ = conn.Write([]byte(b.String()))
+	for i := 0; i < 20; i++ {
+		if r := c.reply(t); r != "+OK" {
+			t.Errorf("pipeline reply %d mismatch: %q", i, r)
+		}
+	}
+
+	// Scan all keys by pages.
+	var (
+		cursor = "0"
+		keys   int
+	)
+	for {
+		c.send(t, "SCAN", cursor, "MATCH", "key*", "COUNT", "7")
+		r := c.reply(t)
+		parts := strings.SplitN(strings.Trim(r, "[]"), " ", 2)
+		cursor = parts[0]
+		if len(parts) > 1 && len(parts[1]) > 2 {
+			keys += len(strings.Fields(strings.Trim(parts[1], "[]")))
+		}
+		if cursor == "0" {
+			break
+		}
+	}
+	if keys != 20 {
+		t.Errorf("scan keys mismatch: need 20 got %d", keys)
+	}
+
+	c.send(t, "INFO")
+	if info := c.reply(t); !strings.Contains(info, "db0:keys=21\r\n") {
+		t.Errorf("info keyspace mismatch: %s", info)
+	}
+	c.expect(t, "+OK", "FLUSHALL")
+	c.expect(t, ":0", "EXISTS", "foo", "key0")
+	c.expect(t, "+OK", "QUIT")
+
+	_ = srv.Close()
+	if err = <-done; err != ErrClosed {
+		t.Errorf("serve must return ErrClosed, got %v", err)
+	}
+}
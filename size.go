@@ -16,8 +16,18 @@ const (
 )
 
 // CacheSize represents memory size types of cache: total, used and free.
+//
+// Also contains count of known missing entries (see Cache.SetMissing) that don't take arenas memory.
 type CacheSize struct {
 	t, u, f MemorySize
+	m       uint64
+}
+
+// NewCacheSize makes cache size with given total, used and free sizes.
+//
+// Uses by remote implementations of Interface.
+func NewCacheSize(total, used, free MemorySize) CacheSize {
+	return CacheSize{t: total, u: used, f: free}
 }
 
 // Total returns total size of cache.
@@ -35,9 +45,14 @@ func (s CacheSize) Free() MemorySize {
 	return s.f
 }
 
+// Missing returns count of known missing entries.
+func (s CacheSize) Missing() uint64 {
+	return s.m
+}
+
 // Equal check is x is equal to s.
 func (s CacheSize) Equal(x CacheSize) bool {
-	return s.t == x.t && s.u == x.u && s.f == x.f
+	return s.t == x.t && s.u == x.u && s.f == x.f && s.m == x.m
 }
 
 // String returns a string representation of size.
@@ -0,0 +1,39 @@
+//go:build go1.21
+
+package cbytecache
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger is a log/slog implementation of StructLogger.
+type SlogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger makes new StructLogger over slog logger. If l omit slog.Default() will use instead.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{l: l}
+}
+
+func (l *SlogLogger) Enabled(level Level) bool {
+	return l.l.Enabled(context.Background(), level.slog())
+}
+
+func (l *SlogLogger) Log(level Level, msg string, fields ...Field) {
+	var buf [8]slog.Attr
+	attrs := buf[:0]
+	for i := 0; i < len(fields); i++ {
+		attrs = append(attrs, slog.Any(fields[i].Key, fields[i].Value))
+	}
+	l.l.LogAttrs(context.Background(), level.slog(), msg, attrs...)
+}
+
+// Convert level to log/slog level.
+func (l Level) slog() slog.Level {
+	return slog.Level(l - LevelInfo)
+}
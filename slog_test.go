@@ -0,0 +1,28 @@
+//go:build go1.21
+
+package cbytecache
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	l := NewSlogLogger(slog.New(h))
+	if l.Enabled(LevelInfo) {
+		t.Error("info level must be disabled")
+	}
+	l.Log(LevelWarn, "keys collision", Field{"bucket", uint32(1)}, Field{"key", "foo"})
+	assertString(t, "level=WARN msg=\"keys collision\" bucket=1 key=foo\n", buf.String())
+}
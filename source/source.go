@@ -0,0 +1,298 @@
+package source
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/koykov/cbytecache"
+)
+
+const (
+	defaultBatchSize     = 64
+	defaultFlushInterval = time.Second
+	defaultQueueSize     = 1024
+
+	// Count of key locks that serialize writes of the same key.
+	keyLocks = 256
+)
+
+var (
+	ErrNoSource = errors.New("no source provided")
+	ErrNoStorer = errors.New("source doesn't support store")
+	ErrClosed   = errors.New("adapter closed")
+)
+
+// Source is the interface of the backing store that wraps the basic Load method.
+//
+// Load returns error wraps cbytecache.ErrNotFound if entry doesn't exist in the store.
+type Source interface {
+	Load(key string) ([]byte, error)
+}
+
+// Storer is the optional interface of the Source that allows to write entries back to the store.
+//
+// Body is valid only inside Store call, implementation must copy it to keep.
+type Storer interface {
+	Store(key string, body []byte) error
+}
+
+// BatchStorer is the optional interface of the Source that allows to write entries in batches (see Config.WriteBehind).
+type BatchStorer interface {
+	StoreBatch(entries []cbytecache.Entry) error
+}
+
+// Config describes adapter params.
+type Config struct {
+	// Source represents backing store.
+	// Mandatory param.
+	Source Source
+	// WriteBehind enables asynchronous writes to the store. Set returns right after cache write and entries are
+	// flushed to the store in batches.
+	// If false, Set writes to the store first (write-through).
+	WriteBehind bool
+	// BatchSize limits count of entries in single write-behind batch.
+	// If this param omit defaultBatchSize (64) will use instead.
+	BatchSize int
+	// FlushInterval indicates how often incomplete write-behind batch flushes.
+	// If this param omit defaultFlushInterval (1 second) will use instead.
+	FlushInterval time.Duration
+	// QueueSize limits count of entries waiting for write-behind. Full queue blocks Set.
+	// If this param omit defaultQueueSize (1024) will use instead.
+	QueueSize int
+	// ErrorHandler receives errors of write-behind flushes.
+	ErrorHandler func(err error)
+}
+
+// Cache is a read-through/write-through adapter that combines cbytecache.Cache with backing store.
+//
+// Get loads missing entries from the source (concurrent misses of the same key coalesce, see Cache.GetOrLoad), Set
+// writes entries to the store synchronously or in background batches.
+type Cache struct {
+	cache *cbytecache.Cache
+	conf  Config
+	st    Storer
+
+	// Writes of the same key lock the same mutex, so store and cache get writes in the same order.
+	kl [keyLocks]sync.Mutex
+
+	q    chan cbytecache.Entry
+	done chan struct{}
+	mux  sync.RWMutex
+	stop bool
+	wg   sync.WaitGroup
+}
+
+// New makes new adapter over given cache and config.
+func New(cache *cbytecache.Cache, conf Config) (*Cache, error) {
+	if cache == nil {
+		return nil, cbytecache.ErrBadCache
+	}
+	if conf.Source == nil {
+		return nil, ErrNoSource
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = defaultBatchSize
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = defaultFlushInterval
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = defaultQueueSize
+	}
+	c := Cache{
+		cache: cache,
+		conf:  conf,
+	}
+	c.st, _ = conf.Source.(Storer)
+	if conf.WriteBehind {
+		if c.st == nil {
+			if _, ok := conf.Source.(BatchStorer); !ok {
+				return nil, ErrNoStorer
+			}
+		}
+		c.q = make(chan cbytecache.Entry, conf.QueueSize)
+		c.done = make(chan struct{})
+		c.wg.Add(1)
+		go c.work()
+	}
+	return &c, nil
+}
+
+// Set writes entry to the store and the cache. Existing cache entry will overwrite.
+//
+// Concurrent writes of the same key serialize, thus cache keeps the same entry as the store. Concurrent load of the
+// key (see Get) doesn't overwrite the entry.
+func (c *Cache) Set(key string, data []byte) error {
+	if !c.conf.WriteBehind {
+		if c.st == nil {
+			return ErrNoStorer
+		}
+		mux := c.keyLock(key)
+		mux.Lock()
+		defer mux.Unlock()
+		if err := c.st.Store(key, data); err != nil {
+			return err
+		}
+		return c.cache.SetEntry(cbytecache.Entry{Key: key, Body: data})
+	}
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	if c.stop {
+		return ErrClosed
+	}
+	mux := c.keyLock(key)
+	mux.Lock()
+	defer mux.Unlock()
+	if err := c.cache.SetEntry(cbytecache.Entry{Key: key, Body: data}); err != nil {
+		return err
+	}
+	c.q <- cbytecache.Entry{Key: key, Body: data}.Copy()
+	return nil
+}
+
+// Get lock of the key.
+func (c *Cache) keyLock(key string) *sync.Mutex {
+	// FNV-1a hash of the key.
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &c.kl[h%keyLocks]
+}
+
+// Get returns entry bytes from the cache or loads it from the source on miss.
+func (c *Cache) Get(key string) ([]byte, error) {
+	return c.GetTo(nil, key)
+}
+
+// GetTo appends entry bytes to dst from the cache or loads it from the source on miss.
+func (c *Cache) GetTo(dst []byte, key string) ([]byte, error) {
+	return c.cache.GetToOrLoad(dst, key, func() ([]byte, error) {
+		return c.conf.Source.Load(key)
+	})
+}
+
+// Delete deletes entry from the cache. The store isn't affected.
+func (c *Cache) Delete(key string) error {
+	return c.cache.Delete(key)
+}
+
+// Extract returns entry bytes and deletes the entry from the cache. The store isn't affected.
+func (c *Cache) Extract(key string) ([]byte, error) {
+	return c.cache.Extract(key)
+}
+
+// Size returns cache size.
+func (c *Cache) Size() cbytecache.CacheSize {
+	return c.cache.Size()
+}
+
+// Close flushes pending write-behind entries and stops the adapter. Underlying cache stays open.
+func (c *Cache) Close() error {
+	if !c.conf.WriteBehind {
+		return nil
+	}
+	c.mux.Lock()
+	if c.stop {
+		c.mux.Unlock()
+		return nil
+	}
+	c.stop = true
+	close(c.done)
+	c.mux.Unlock()
+	c.wg.Wait()
+	return nil
+}
+
+// Write-behind worker.
+func (c *Cache) work() {
+	defer c.wg.Done()
+	var (
+		batch []cbytecache.Entry
+		pos   = make(map[string]int)
+	)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := c.flush(batch); err != nil && c.conf.ErrorHandler != nil {
+			c.conf.ErrorHandler(err)
+		}
+		batch = batch[:0]
+		for k := range pos {
+			delete(pos, k)
+		}
+	}
+	add := func(e cbytecache.Entry) {
+		// Keep only the latest version of the key in the batch.
+		if i, ok := pos[e.Key]; ok {
+			batch[i] = e
+			return
+		}
+		pos[e.Key] = len(batch)
+		batch = append(batch, e)
+		if len(batch) >= c.conf.BatchSize {
+			flush()
+		}
+	}
+
+	ticker := time.NewTicker(c.conf.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-c.q:
+			add(e)
+		case <-ticker.C:
+			flush()
+		case <-c.done:
+			// Writers are stopped, drain the queue.
+			for {
+				select {
+				case e := <-c.q:
+					add(e)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Write batch to the store.
+func (c *Cache) flush(batch []cbytecache.Entry) error {
+	if bs, ok := c.conf.Source.(BatchStorer); ok {
+		return bs.StoreBatch(batch)
+	}
+	var err error
+	for i := range batch {
+		if err1 := c.st.Store(batch[i].Key, batch[i].Body); err1 != nil && err == nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// ExpireListener is a cbytecache.Listener implementation that writes expired entries back to the store.
+//
+// Use it as cbytecache.Config.ExpireListener to flush expiring entries (e.g. modified via write-behind) to the store.
+type ExpireListener struct {
+	st Storer
+}
+
+// NewExpireListener makes new listener over given store.
+func NewExpireListener(st Storer) (*ExpireListener, error) {
+	if st == nil {
+		return nil, ErrNoStorer
+	}
+	return &ExpireListener{st: st}, nil
+}
+
+func (l *ExpireListener) Listen(e cbytecache.Entry) error {
+	return l.st.Store(e.Key, e.Body)
+}
+
+var _ cbytecache.Interface = (*Cache)(nil)
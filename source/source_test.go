@@ -0,0 +1,155 @@
+package source
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+type testStore struct {
+	mux     sync.Mutex
+	data    map[string]string
+	loads   int
+	batches int
+}
+
+func (s *testStore) Load(key string) ([]byte, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.loads++
+	if v, ok := s.data[key]; ok {
+		return []byte(v), nil
+	}
+	return nil, cbytecache.ErrNotFound
+}
+
+func (s *testStore) Store(key string, body []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.data[key] = string(body)
+	return nil
+}
+
+func (s *testStore) get(key string) (string, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+type testBatchStore struct {
+	testStore
+}
+
+func (s *testBatchStore) StoreBatch(entries []cbytecache.Entry) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.batches++
+	for _, e := range entries {
+		s.data[e.Key] = string(e.Body)
+	}
+	return nil
+}
+
+func newCache(t *testing.T) *cbytecache.Cache {
+	cache, err := cbytecache.New(cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
+
+func TestReadThrough(t *testing.T) {
+	st := &testStore{data: map[string]string{"foo": "bar"}}
+	c, err := New(newCache(t), Config{Source: st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if b, err := c.Get("foo"); err != nil || string(b) != "bar" {
+			t.Fatalf("read through mismatch: %s %v", b, err)
+		}
+	}
+	if st.loads != 1 {
+		t.Errorf("loads count mismatch: need 1 got %d", st.loads)
+	}
+	if _, err = c.Get("missing"); err != cbytecache.ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	st := &testStore{data: map[string]string{}}
+	c, _ := New(newCache(t), Config{Source: st})
+	if err := c.Set("foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("foo", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := st.get("foo"); v != "baz" {
+		t.Errorf("store mismatch: need baz got %s", v)
+	}
+	if b, _ := c.Get("foo"); string(b) != "baz" {
+		t.Errorf("cache mismatch: need baz got %s", b)
+	}
+
+	// Concurrent writes of the same key keep cache and store consistent.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = c.Set("bar", []byte(fmt.Sprintf("%d.%d", i, j)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	v, _ := st.get("bar")
+	if b, _ := c.Get("bar"); string(b) != v {
+		t.Errorf("cache mismatch: need %s got %s", v, b)
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	st := &testBatchStore{testStore{data: map[string]string{}}}
+	c, err := New(newCache(t), Config{Source: st, WriteBehind: true, BatchSize: 10, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 25; i++ {
+		_ = c.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("body%d", i)))
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if st.batches != 3 {
+		t.Errorf("batches count mismatch: need 3 got %d", st.batches)
+	}
+	for i := 0; i < 25; i++ {
+		if v, _ := st.get(fmt.Sprintf("key%d", i)); v != fmt.Sprintf("body%d", i) {
+			t.Errorf("key%d store mismatch: %s", i, v)
+		}
+	}
+	if err = c.Set("foo", []byte("bar")); err != ErrClosed {
+		t.Errorf("error mismatch: need ErrClosed got %v", err)
+	}
+}
+
+func TestExpireListener(t *testing.T) {
+	st := &testStore{data: map[string]string{}}
+	l, err := NewExpireListener(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Listen(cbytecache.Entry{Key: "foo", Body: []byte("bar")})
+	if v, _ := st.get("foo"); v != "bar" {
+		t.Errorf("store mismatch: need bar got %s", v)
+	}
+}
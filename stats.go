@@ -0,0 +1,62 @@
+package cbytecache
+
+import "time"
+
+// Stats represents cache statistics snapshot.
+type Stats struct {
+	// Size of the whole cache.
+	Size CacheSize
+	// Entries represents count of alive entries.
+	Entries uint64
+	// Buckets contains per-bucket statistics.
+	Buckets []BucketStats
+}
+
+// BucketStats represents bucket statistics snapshot.
+type BucketStats struct {
+	// Size of the bucket.
+	Size CacheSize
+	// Entries represents count of alive entries in the bucket.
+	Entries uint32
+	// Arenas statistics: total, full and empty arenas counts.
+	Arenas, ArenasFull, ArenasEmpty uint32
+	// Last maintenance operations timestamps.
+	LastEvict, LastVacuum time.Time
+	// Last maintenance operations durations.
+	EvictDuration, VacuumDuration time.Duration
+}
+
+// Stats returns cache statistics snapshot.
+func (c *Cache) Stats() (r Stats) {
+	r.Buckets = make([]BucketStats, len(c.buckets))
+	_ = c.buckets[len(c.buckets)-1]
+	for i := 0; i < len(c.buckets); i++ {
+		bs := &r.Buckets[i]
+		c.buckets[i].stats(bs)
+		r.Size.t += bs.Size.t
+		r.Size.u += bs.Size.u
+		r.Size.f += bs.Size.f
+		r.Size.m += bs.Size.m
+		r.Entries += uint64(bs.Entries)
+	}
+	return
+}
+
+// Collect bucket statistics to dst.
+func (b *bucket) stats(dst *BucketStats) {
+	t, u, f := b.size.snapshot()
+	dst.Size = CacheSize{t: MemorySize(t), u: MemorySize(u), f: MemorySize(f), m: uint64(b.size.missingSnapshot())}
+
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	// Expired entries stay in the index till eviction, so count only alive ones.
+	now := b.now()
+	for i := 0; i < len(b.entry); i++ {
+		if e := &b.entry[i]; !e.invalid() && e.expire >= now {
+			dst.Entries++
+		}
+	}
+	dst.Arenas, dst.ArenasFull, dst.ArenasEmpty = b.queue.stat()
+	dst.LastEvict, dst.LastVacuum = b.lastEvc, b.lastVac
+	dst.EvictDuration, dst.VacuumDuration = b.durEvc, b.durVac
+}
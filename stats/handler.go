@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/koykov/cbytecache"
+)
+
+var ErrPublished = errors.New("expvar name already published")
+
+// Protects check and publish of expvar variable.
+var pubMux sync.Mutex
+
+// Handler is a http.Handler implementation that renders cache statistics as JSON.
+type Handler struct {
+	cache *cbytecache.Cache
+}
+
+// NewHandler makes new Handler instance with given cache.
+func NewHandler(cache *cbytecache.Cache) (*Handler, error) {
+	if cache == nil {
+		return nil, cbytecache.ErrBadCache
+	}
+	h := Handler{cache: cache}
+	return &h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	s := Snapshot(h.cache)
+	if v := r.URL.Query().Get("buckets"); len(v) > 0 {
+		if ok, _ := strconv.ParseBool(v); !ok {
+			s.Buckets = nil
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// Publish registers cache statistics in expvar under given name.
+//
+// Statistics will collect on every read of expvar variable. Returns ErrPublished if name already in use.
+func Publish(name string, cache *cbytecache.Cache) error {
+	if cache == nil {
+		return cbytecache.ErrBadCache
+	}
+	pubMux.Lock()
+	defer pubMux.Unlock()
+	if expvar.Get(name) != nil {
+		return ErrPublished
+	}
+	expvar.Publish(name, expvar.Func(func() any { return Snapshot(cache) }))
+	return nil
+}
+
+// Stats is a JSON representation of cbytecache.Stats.
+type Stats struct {
+	Size    Size          `json:"size"`
+	Entries uint64        `json:"entries"`
+	Buckets []BucketStats `json:"buckets,omitempty"`
+}
+
+// BucketStats is a JSON representation of cbytecache.BucketStats.
+type BucketStats struct {
+	Size        Size   `json:"size"`
+	Entries     uint32 `json:"entries"`
+	Arenas      Arenas `json:"arenas"`
+	Maintenance struct {
+		Evict  Op `json:"evict"`
+		Vacuum Op `json:"vacuum"`
+	} `json:"maintenance"`
+}
+
+// Size is a JSON representation of cbytecache.CacheSize.
+type Size struct {
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+	Free  uint64 `json:"free"`
+}
+
+// Arenas represents arenas counters.
+type Arenas struct {
+	Total uint32 `json:"total"`
+	Full  uint32 `json:"full"`
+	Empty uint32 `json:"empty"`
+}
+
+// Op represents last maintenance operation timings.
+type Op struct {
+	Last     *time.Time `json:"last,omitempty"`
+	Duration string     `json:"duration"`
+}
+
+// Snapshot collects cache statistics in JSON-friendly form.
+func Snapshot(cache *cbytecache.Cache) Stats {
+	raw := cache.Stats()
+	s := Stats{
+		Size:    convSize(raw.Size),
+		Entries: raw.Entries,
+		Buckets: make([]BucketStats, len(raw.Buckets)),
+	}
+	for i := range raw.Buckets {
+		src, dst := &raw.Buckets[i], &s.Buckets[i]
+		dst.Size = convSize(src.Size)
+		dst.Entries = src.Entries
+		dst.Arenas = Arenas{Total: src.Arenas, Full: src.ArenasFull, Empty: src.ArenasEmpty}
+		dst.Maintenance.Evict = convOp(src.LastEvict, src.EvictDuration)
+		dst.Maintenance.Vacuum = convOp(src.LastVacuum, src.VacuumDuration)
+	}
+	return s
+}
+
+func convSize(s cbytecache.CacheSize) Size {
+	return Size{Total: uint64(s.Total()), Used: uint64(s.Used()), Free: uint64(s.Free())}
+}
+
+func convOp(last time.Time, dur time.Duration) (op Op) {
+	if !last.IsZero() {
+		op.Last = &last
+	}
+	op.Duration = dur.String()
+	return
+}
+
+var _, _ = NewHandler, Publish
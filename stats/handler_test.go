@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestHandler(t *testing.T) {
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 4
+	cache, err := cbytecache.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+	for _, k := range []string{"foo", "bar", "qwe"} {
+		if err = cache.Set(k, []byte("payload of "+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := NewHandler(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	t.Run("full", func(t *testing.T) {
+		s := fetch(t, srv.URL)
+		if s.Entries != 3 {
+			t.Errorf("entries mismatch: need 3 got %d", s.Entries)
+		}
+		if len(s.Buckets) != 4 {
+			t.Errorf("buckets mismatch: need 4 got %d", len(s.Buckets))
+		}
+		if size := cache.Size(); s.Size.Used != uint64(size.Used()) {
+			t.Errorf("used size mismatch: need %d got %d", size.Used(), s.Size.Used)
+		}
+	})
+	t.Run("short", func(t *testing.T) {
+		if s := fetch(t, srv.URL+"?buckets=false"); len(s.Buckets) != 0 {
+			t.Errorf("buckets must be omitted, got %d", len(s.Buckets))
+		}
+	})
+	t.Run("method", func(t *testing.T) {
+		resp, err := http.Post(srv.URL, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("status mismatch: need %d got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+		}
+	})
+	t.Run("publish", func(t *testing.T) {
+		if err := Publish("cbytecache_test", cache); err != nil {
+			t.Fatal(err)
+		}
+		if err := Publish("cbytecache_test", cache); err != ErrPublished {
+			t.Errorf("error mismatch: need ErrPublished got %v", err)
+		}
+	})
+}
+
+func fetch(t *testing.T, url string) (s Stats) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err = json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package cbytecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+func TestStats(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 4
+	conf.Clock = clock.NewClock()
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	for _, k := range []string{"foo", "bar", "qwe"} {
+		_ = cache.Set(k, []byte("payload of "+k))
+	}
+	_ = cache.Delete("bar")
+	if s := cache.Stats(); s.Entries != 2 {
+		t.Errorf("entries mismatch: need 2 got %d", s.Entries)
+	}
+	// Expired but not evicted entries must not count.
+	conf.Clock.Jump(time.Minute * 2)
+	if s := cache.Stats(); s.Entries != 0 {
+		t.Errorf("entries mismatch: need 0 got %d", s.Entries)
+	}
+}
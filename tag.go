@@ -0,0 +1,76 @@
+package cbytecache
+
+// SetWithTags sets entry bytes to the cache together with tags.
+//
+// Tags allow to delete or count all entries related to the same object (e.g. database row), see DeleteByTag and
+// CountByTag. Tags store as hashes computed by Config.Hasher, see Entry.Tags.
+func (c *Cache) SetWithTags(key string, data []byte, tags ...string) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if err := c.checkEntry(key, uint32(len(data))); err != nil {
+		return err
+	}
+	if len(tags) > MaxTags {
+		return ErrTooManyTags
+	}
+	var buf [MaxTags]uint64
+	th := buf[:0]
+	for i := 0; i < len(tags); i++ {
+		th = append(th, c.TagHash(tags[i]))
+	}
+	tn, err := c.tenantOf(key)
+	if err != nil {
+		return err
+	}
+	h := c.config.Hasher.Sum64(key)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.set(key, h, data, 0, tn, th)
+}
+
+// DeleteByTag removes all entries tagged with tag. Returns count of deleted alive entries.
+//
+// Buckets under maintenance skip and reported by ErrBucketService error.
+func (c *Cache) DeleteByTag(tag string) (int, error) {
+	return c.DeleteByTagHash(c.TagHash(tag))
+}
+
+// DeleteByTagHash removes all entries tagged with tag hash th. Returns count of deleted alive entries.
+func (c *Cache) DeleteByTagHash(th uint64) (r int, err error) {
+	if err = c.checkCache(cacheStatusActive); err != nil {
+		return
+	}
+	for i := 0; i < len(c.buckets); i++ {
+		n, err1 := c.buckets[i].delByTag(th)
+		r += n
+		if err1 != nil {
+			err = err1
+		}
+	}
+	return
+}
+
+// CountByTag returns count of alive entries tagged with tag.
+func (c *Cache) CountByTag(tag string) (int, error) {
+	return c.CountByTagHash(c.TagHash(tag))
+}
+
+// CountByTagHash returns count of alive entries tagged with tag hash th.
+func (c *Cache) CountByTagHash(th uint64) (r int, err error) {
+	if err = c.checkCache(cacheStatusActive); err != nil {
+		return
+	}
+	for i := 0; i < len(c.buckets); i++ {
+		n, err1 := c.buckets[i].countByTag(th)
+		r += n
+		if err1 != nil {
+			err = err1
+		}
+	}
+	return
+}
+
+// TagHash returns hash of tag as it stores in Entry.Tags.
+func (c *Cache) TagHash(tag string) uint64 {
+	return c.config.Hasher.Sum64(tag)
+}
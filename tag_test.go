@@ -0,0 +1,115 @@
+package cbytecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koykov/clock"
+	"github.com/koykov/hash/fnv"
+)
+
+// Dump writer that spoils tags of received entries.
+type spoilTagsWriter struct{}
+
+func (spoilTagsWriter) Write(e Entry) (int, error) {
+	for i := range e.Tags {
+		e.Tags[i] = 0
+	}
+	return e.Size(), nil
+}
+
+func (spoilTagsWriter) Flush() error { return nil }
+
+func TestTag(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 4
+	conf.Clock = clock.NewClock()
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	count := func(tag string) int {
+		c, err := cache.CountByTag(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	_ = cache.SetWithTags("user:1", []byte("foo"), "row:1")
+	_ = cache.SetWithTags("user:1:profile", []byte("bar"), "row:1", "profile", "row:1")
+	_ = cache.SetWithTags("user:2", []byte("baz"), "row:2")
+	_ = cache.Set("plain", []byte("qux"))
+	if c := count("row:1"); c != 2 {
+		t.Errorf("count mismatch: need 2 got %d", c)
+	}
+	if err = cache.SetWithTags("many", []byte("x"), make([]string, MaxTags+1)...); err != ErrTooManyTags {
+		t.Errorf("error mismatch: need ErrTooManyTags got %v", err)
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		_ = cache.Delete("user:1:profile")
+		if c := count("row:1"); c != 1 {
+			t.Errorf("count mismatch: need 1 got %d", c)
+		}
+		if c := count("profile"); c != 0 {
+			t.Errorf("count mismatch: need 0 got %d", c)
+		}
+	})
+	t.Run("dump", func(t *testing.T) {
+		var w dumpBuf
+		if err := cache.DumpTo(&w); err != nil {
+			t.Fatal(err)
+		}
+		cache1, err := New(DefaultConfig(time.Minute, &fnv.Hasher{}, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache1.Close() }()
+		for i := range w.entries {
+			if err = cache1.SetEntry(w.entries[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c, _ := cache1.CountByTag("row:2"); c != 1 {
+			t.Errorf("count mismatch after load: need 1 got %d", c)
+		}
+		// Dumped tags must not share memory with tags index.
+		if err = cache.DumpTo(spoilTagsWriter{}); err != nil {
+			t.Fatal(err)
+		}
+		if c := count("row:2"); c != 1 {
+			t.Errorf("count mismatch after dump: need 1 got %d", c)
+		}
+	})
+	t.Run("delete by tag", func(t *testing.T) {
+		_ = cache.SetWithTags("user:3", []byte("foo"), "row:2")
+		c, err := cache.DeleteByTag("row:2")
+		if err != nil || c != 2 {
+			t.Errorf("delete mismatch: need 2 got %d (%v)", c, err)
+		}
+		if _, err = cache.Get("user:2"); err != ErrNotFound {
+			t.Errorf("error mismatch: need ErrNotFound got %v", err)
+		}
+		if b, _ := cache.Get("user:1"); string(b) != "foo" {
+			t.Errorf("entry of another tag must keep, got %s", b)
+		}
+	})
+	t.Run("evict", func(t *testing.T) {
+		conf.Clock.Jump(time.Minute * 2)
+		if c := count("row:1"); c != 0 {
+			t.Errorf("expired entries must not count, got %d", c)
+		}
+		if c, _ := cache.DeleteByTag("row:1"); c != 0 {
+			t.Errorf("expired entries must not count on delete, got %d", c)
+		}
+		_ = cache.Evict()
+		for _, b := range cache.buckets {
+			if len(b.tags) != 0 || len(b.etags) != 0 {
+				t.Errorf("tags index of bucket %d must be empty after eviction", b.idx)
+			}
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package cbytecache
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// TenantFunc derives tenant name from entry key. Empty name means entry doesn't belong to any tenant.
+type TenantFunc func(key string) string
+
+// TenantStats represents tenant memory usage snapshot.
+type TenantStats struct {
+	// Name of the tenant.
+	Name string
+	// Quota of the tenant. Zero means no limit.
+	Quota MemorySize
+	// Used represents size of alive tenant entries.
+	Used MemorySize
+}
+
+// Registry of cache tenants.
+type tenantRegistry struct {
+	mux sync.RWMutex
+	idx map[string]uint16
+	// Max count of tenants.
+	limit int
+	// Tenants names and quotas ordered by ID, ID of tenant is its position + 1.
+	name  []string
+	quota []MemorySize
+}
+
+// PrefixTenant makes TenantFunc that uses key prefix before separator sep as tenant name.
+//
+// Keys without separator don't belong to any tenant.
+func PrefixTenant(sep string) TenantFunc {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i > 0 {
+			return key[:i]
+		}
+		return ""
+	}
+}
+
+// SetWithTenant sets entry bytes to the cache on behalf of tenant.
+//
+// Explicit tenant takes precedence over Config.TenantFunc. Empty tenant means entry doesn't belong to any tenant.
+func (c *Cache) SetWithTenant(key string, data []byte, tenant string) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if err := c.checkEntry(key, uint32(len(data))); err != nil {
+		return err
+	}
+	tn, err := c.tnr.register(tenant)
+	if err != nil {
+		return err
+	}
+	h := c.config.Hasher.Sum64(key)
+	bkt := c.buckets[h%uint64(c.config.Buckets)]
+	return bkt.set(key, h, data, 0, tn, nil)
+}
+
+// SetTenantQuota sets memory quota of tenant. Zero quota means no limit.
+//
+// Writes over the quota fail with ErrQuotaExceeded. Quota splits evenly among buckets the same way as cache capacity
+// does. If tenant already exceeds new quota, its oldest entries will delete during the next eviction.
+func (c *Cache) SetTenantQuota(tenant string, quota MemorySize) error {
+	if err := c.checkCache(cacheStatusActive); err != nil {
+		return err
+	}
+	if len(tenant) == 0 {
+		return ErrBadTenant
+	}
+	tn, err := c.tnr.register(tenant)
+	if err != nil {
+		return err
+	}
+	c.tnr.mux.Lock()
+	c.tnr.quota[tn-1] = quota
+	c.tnr.mux.Unlock()
+	bq := bucketQuota(quota, c.config.Buckets)
+	for i := 0; i < len(c.buckets); i++ {
+		c.buckets[i].setTenantQuota(tn, bq)
+	}
+	return ErrOK
+}
+
+// Tenants returns memory usage snapshot of all known tenants ordered by registration.
+func (c *Cache) Tenants() []TenantStats {
+	r := &c.tnr
+	r.mux.RLock()
+	buf := make([]TenantStats, len(r.name))
+	for i := 0; i < len(r.name); i++ {
+		buf[i].Name, buf[i].Quota = r.name[i], r.quota[i]
+	}
+	r.mux.RUnlock()
+
+	for i := 0; i < len(buf); i++ {
+		tn := uint16(i + 1)
+		for j := 0; j < len(c.buckets); j++ {
+			buf[i].Used += MemorySize(c.buckets[j].tenantSize(tn))
+		}
+	}
+	return buf
+}
+
+// Get tenant ID of key using Config.TenantFunc.
+func (c *Cache) tenantOf(key string) (uint16, error) {
+	if c.config.TenantFunc == nil {
+		return 0, ErrOK
+	}
+	return c.tnr.register(c.config.TenantFunc(key))
+}
+
+// Get ID of tenant or register new one.
+//
+// Returns zero ID if tenant is empty and ErrTenantLimit if registry is full.
+func (r *tenantRegistry) register(tenant string) (uint16, error) {
+	if len(tenant) == 0 {
+		return 0, ErrOK
+	}
+	r.mux.RLock()
+	tn, ok := r.idx[tenant]
+	r.mux.RUnlock()
+	if ok {
+		return tn, ErrOK
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if tn, ok = r.idx[tenant]; ok {
+		return tn, ErrOK
+	}
+	if len(r.name) >= r.limit {
+		return 0, ErrTenantLimit
+	}
+	if r.idx == nil {
+		r.idx = make(map[string]uint16)
+	}
+	// Tenant may point to key bytes, so copy it.
+	tenant = string([]byte(tenant))
+	r.name = append(r.name, tenant)
+	r.quota = append(r.quota, 0)
+	tn = uint16(len(r.name))
+	r.idx[tenant] = tn
+	return tn, ErrOK
+}
+
+// Split quota among buckets.
+func bucketQuota(quota MemorySize, buckets uint) uint32 {
+	bq := uint64(quota) / uint64(buckets)
+	if quota > 0 && bq == 0 {
+		bq = 1
+	}
+	if bq > math.MaxUint32 {
+		bq = math.MaxUint32
+	}
+	return uint32(bq)
+}
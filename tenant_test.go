@@ -0,0 +1,184 @@
+package cbytecache
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/koykov/hash/fnv"
+)
+
+func TestTenant(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.Buckets = 4
+	conf.TenantFunc = PrefixTenant(":")
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	body := bytes.Repeat([]byte("x"), 256)
+	usage := func(tenant string) TenantStats {
+		for _, ts := range cache.Tenants() {
+			if ts.Name == tenant {
+				return ts
+			}
+		}
+		return TenantStats{}
+	}
+
+	t.Run("quota", func(t *testing.T) {
+		if err := cache.SetTenantQuota("noisy", 8*Kilobyte); err != nil {
+			t.Fatal(err)
+		}
+		var i int
+		for i = 0; i < 1000; i++ {
+			if err = cache.Set("noisy:"+strconv.Itoa(i), body); err != nil {
+				break
+			}
+		}
+		if err != ErrQuotaExceeded {
+			t.Fatalf("error mismatch: need ErrQuotaExceeded got %v", err)
+		}
+		if u := usage("noisy"); u.Used == 0 || u.Used > u.Quota || u.Quota != 8*Kilobyte {
+			t.Errorf("usage mismatch: %+v", u)
+		}
+		// Other tenants and keys without tenant aren't limited.
+		for j := 0; j < i*2; j++ {
+			if err := cache.Set("quiet:"+strconv.Itoa(j), body); err != nil {
+				t.Fatal(err)
+			}
+			if err := cache.Set(strconv.Itoa(j), body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if u := usage("quiet"); u.Used <= usage("noisy").Used {
+			t.Errorf("usage mismatch: %+v", u)
+		}
+	})
+	t.Run("explicit", func(t *testing.T) {
+		if err := cache.SetWithTenant("foo", body, "noisy"); err != ErrQuotaExceeded {
+			t.Errorf("error mismatch: need ErrQuotaExceeded got %v", err)
+		}
+		if err := cache.SetWithTenant("foo", body, "other"); err != nil {
+			t.Fatal(err)
+		}
+		if u := usage("other"); u.Used == 0 {
+			t.Errorf("usage mismatch: %+v", u)
+		}
+		if err := cache.Delete("foo"); err != nil {
+			t.Fatal(err)
+		}
+		if u := usage("other"); u.Used != 0 {
+			t.Errorf("usage must be zero after delete: %+v", u)
+		}
+	})
+	t.Run("reclaim", func(t *testing.T) {
+		before := usage("quiet")
+		if err := cache.SetTenantQuota("quiet", 4*Kilobyte); err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.Evict(); err != nil {
+			t.Fatal(err)
+		}
+		after := usage("quiet")
+		if after.Used > after.Quota || after.Used == 0 || after.Used >= before.Used {
+			t.Errorf("usage mismatch: before %+v after %+v", before, after)
+		}
+		// Oldest entries reclaim first.
+		if _, err := cache.Get("quiet:0"); err != ErrNotFound {
+			t.Errorf("error mismatch: need ErrNotFound got %v", err)
+		}
+		if _, err := cache.Get("0"); err != nil {
+			t.Errorf("entry without tenant must keep: %v", err)
+		}
+	})
+}
+
+func TestTenantLimit(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 0)
+	conf.TenantFunc = PrefixTenant(":")
+	conf.TenantLimit = 2
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	_ = cache.Set("a:foo", []byte("bar"))
+	_ = cache.Set("b:foo", []byte("bar"))
+	if err = cache.Set("c:foo", []byte("bar")); err != ErrTenantLimit {
+		t.Errorf("error mismatch: need ErrTenantLimit got %v", err)
+	}
+	// Known tenants and keys without tenant still work.
+	if err = cache.Set("a:bar", []byte("bar")); err != nil {
+		t.Error(err)
+	}
+	if err = cache.Set("foo", []byte("bar")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTenantReclaimSpace(t *testing.T) {
+	conf := DefaultConfig(time.Minute, &fnv.Hasher{}, 64*Kilobyte)
+	conf.Buckets = 1
+	conf.ArenaCapacity = 4 * Kilobyte
+	conf.TenantFunc = PrefixTenant(":")
+	cache, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	body := bytes.Repeat([]byte("x"), 512)
+	// Noisy tenant takes the whole bucket.
+	var i int
+	for i = 0; i < 1000; i++ {
+		if err = cache.Set("noisy:"+strconv.Itoa(i), body); err != nil {
+			break
+		}
+	}
+	if err != ErrNoSpace {
+		t.Fatalf("error mismatch: need ErrNoSpace got %v", err)
+	}
+	// Write of other tenant reclaims space from the noisy one.
+	for j := 0; j < 4; j++ {
+		if err = cache.Set("quiet:"+strconv.Itoa(j), body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, err := cache.Get("quiet:0"); err != nil || !bytes.Equal(b, body) {
+		t.Errorf("quiet entry lost: %v", err)
+	}
+	if b, err := cache.Get("noisy:" + strconv.Itoa(i-1)); err != nil || !bytes.Equal(b, body) {
+		t.Errorf("the newest noisy entry lost: %v", err)
+	}
+	if _, err = cache.Get("noisy:0"); err != ErrNotFound {
+		t.Errorf("error mismatch: need ErrNotFound got %v", err)
+	}
+
+	t.Run("keep on fail", func(t *testing.T) {
+		cache, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cache.Close() }()
+		// Entry of quiet tenant at the head blocks recycling of the first arena.
+		_ = cache.Set("noisy:0", body)
+		_ = cache.Set("quiet:0", body)
+		for i := 1; i < 1000; i++ {
+			if err = cache.Set("noisy:"+strconv.Itoa(i), body); err != nil {
+				break
+			}
+		}
+		if err = cache.Set("quiet:1", body); err != ErrNoSpace {
+			t.Errorf("error mismatch: need ErrNoSpace got %v", err)
+		}
+		// Failed reclaim must not delete entries of the noisy tenant.
+		if b, err := cache.Get("noisy:0"); err != nil || !bytes.Equal(b, body) {
+			t.Errorf("noisy entry lost: %v", err)
+		}
+	})
+}
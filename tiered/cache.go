@@ -0,0 +1,144 @@
+package tiered
+
+import (
+	"github.com/koykov/cbytecache"
+)
+
+// Cache is a two-tier cache: in-memory cbytecache and on-disk spill tier.
+//
+// Entries that don't fit memory (cbytecache.ErrNoSpace) are demoted to disk and promoted back to memory on read if
+// memory has free space. This allows to hold working set larger than memory capacity.
+//
+// Only writes rejected by memory tier go to disk. Entries evicted or expired in memory tier are never demoted, they
+// just disappear.
+type Cache struct {
+	mem   *cbytecache.Cache
+	disk  *Disk
+	conf  *cbytecache.Config
+	clock cbytecache.Clock
+}
+
+// New makes new tiered cache with given config of memory tier and path of disk segment file.
+//
+// Disk segment survives restarts: existing file restores on start.
+func New(conf *cbytecache.Config, path string) (*Cache, error) {
+	if conf == nil {
+		return nil, cbytecache.ErrBadConfig
+	}
+	// Share the clock with memory tier.
+	conf = conf.Copy()
+	if conf.Clock == nil {
+		conf.Clock = &cbytecache.NativeClock{}
+	}
+	mem, err := cbytecache.New(conf)
+	if err != nil {
+		return nil, err
+	}
+	c := Cache{
+		mem:   mem,
+		conf:  conf,
+		clock: conf.Clock,
+	}
+	if c.disk, err = OpenDisk(path, c.now); err != nil {
+		_ = mem.Close()
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Set sets entry bytes to memory tier or to disk tier if memory has no space.
+func (c *Cache) Set(key string, data []byte) error {
+	err := c.mem.Set(key, data)
+	switch err {
+	case nil:
+		// Drop possibly outdated copy from disk.
+		return c.disk.Delete(key)
+	case cbytecache.ErrNoSpace:
+		// Overwrites possible disk copy.
+		return c.disk.Put(cbytecache.Entry{Key: key, Body: data, Expire: c.expire()})
+	default:
+		return err
+	}
+}
+
+// Get returns entry bytes.
+func (c *Cache) Get(key string) ([]byte, error) {
+	return c.GetTo(nil, key)
+}
+
+// GetTo appends entry bytes to dst. Entries found on disk promote to memory.
+func (c *Cache) GetTo(dst []byte, key string) ([]byte, error) {
+	dst1, err := c.mem.GetTo(dst, key)
+	if err != cbytecache.ErrNotFound {
+		return dst1, err
+	}
+	e, err := c.disk.GetTo(dst, key)
+	if err != nil {
+		return e.Body, err
+	}
+	body := e.Body[len(dst):]
+	if err = c.mem.SetEntry(cbytecache.Entry{Key: key, Body: body, Expire: e.Expire}); err == nil {
+		_ = c.disk.Delete(key)
+	}
+	return e.Body, nil
+}
+
+// Delete deletes entry from both tiers.
+func (c *Cache) Delete(key string) error {
+	if err := c.mem.Delete(key); err != nil {
+		return err
+	}
+	return c.disk.Delete(key)
+}
+
+// Extract returns entry bytes and deletes the entry from both tiers.
+func (c *Cache) Extract(key string) ([]byte, error) {
+	body, err := c.mem.Extract(key)
+	if err == cbytecache.ErrNotFound {
+		var e cbytecache.Entry
+		if e, err = c.disk.GetTo(nil, key); err != nil {
+			return nil, err
+		}
+		body = e.Body
+	}
+	if err != nil {
+		return nil, err
+	}
+	return body, c.disk.Delete(key)
+}
+
+// Size returns size of memory tier.
+func (c *Cache) Size() cbytecache.CacheSize {
+	return c.mem.Size()
+}
+
+// Memory returns memory tier.
+func (c *Cache) Memory() *cbytecache.Cache {
+	return c.mem
+}
+
+// Disk returns disk tier.
+func (c *Cache) Disk() *Disk {
+	return c.disk
+}
+
+// Close closes both tiers.
+func (c *Cache) Close() error {
+	err := c.mem.Close()
+	if err1 := c.disk.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// Get expire timestamp of new entry.
+func (c *Cache) expire() uint32 {
+	return uint32(c.clock.Now().Add(c.conf.ExpireInterval).Unix())
+}
+
+// Get current timestamp.
+func (c *Cache) now() uint32 {
+	return uint32(c.clock.Now().Unix())
+}
+
+var _ cbytecache.Interface = (*Cache)(nil)
@@ -0,0 +1,332 @@
+package tiered
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/koykov/byteconv"
+	"github.com/koykov/cbytecache"
+)
+
+const (
+	// Record header: expire (4 bytes), key length (2 bytes), body length (4 bytes).
+	headerSize = 10
+	// Body length of tombstone record.
+	tombstone = math.MaxUint32
+
+	defaultCompactRatio   = 0.5
+	defaultCompactMinSize = 1 << 20
+)
+
+// Disk is an on-disk entries store: append-only segment file with in-memory offset index.
+//
+// Overwrite and delete append new records, old records become garbage. Expired records become garbage too. Segment
+// compacts when garbage exceeds the ratio of segment size.
+type Disk struct {
+	mux  sync.RWMutex
+	path string
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+	dead int64
+	max  int64
+	idx  map[string]location
+	// Timestamp of the last expired records check.
+	expireAt uint32
+
+	now          func() uint32
+	compactRatio float64
+	compactMin   int64
+	hdr          [headerSize]byte
+}
+
+// Record location in segment.
+type location struct {
+	off    int64
+	klen   uint16
+	blen   uint32
+	expire uint32
+}
+
+// Full size of the record.
+func (l location) size() int64 {
+	return headerSize + int64(l.klen) + int64(l.blen)
+}
+
+// OpenDisk opens or creates the segment file and restores the index.
+//
+// Param now returns current unix timestamp to drop expired entries.
+func OpenDisk(path string, now func() uint32) (*Disk, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	d := Disk{
+		path:         path,
+		f:            f,
+		idx:          make(map[string]location),
+		now:          now,
+		compactRatio: defaultCompactRatio,
+		compactMin:   defaultCompactMinSize,
+	}
+	if err = d.restore(); err == nil {
+		_, err = f.Seek(d.size, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	d.w = bufio.NewWriter(f)
+	return &d, nil
+}
+
+// SetMaxSize limits segment file size. Put returns cbytecache.ErrNoSpace if entry doesn't fit even after compaction.
+// Zero size means no limit.
+func (d *Disk) SetMaxSize(size cbytecache.MemorySize) *Disk {
+	d.mux.Lock()
+	d.max = int64(size)
+	d.mux.Unlock()
+	return d
+}
+
+// Put appends entry to the segment.
+func (d *Disk) Put(e cbytecache.Entry) error {
+	if len(e.Key) > cbytecache.MaxKeySize || uint64(len(e.Body)) >= tombstone {
+		return cbytecache.ErrEntryTooBig
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.putLF(e.Key, e.Body, uint32(len(e.Body)), e.Expire)
+}
+
+// GetTo appends entry body to dst. Expired entries are considered as missing.
+func (d *Disk) GetTo(dst []byte, key string) (cbytecache.Entry, error) {
+	d.mux.RLock()
+	loc, ok := d.idx[key]
+	if !ok || loc.expire < d.now() {
+		d.mux.RUnlock()
+		return cbytecache.Entry{Key: key, Body: dst}, cbytecache.ErrNotFound
+	}
+	off := len(dst)
+	dst = append(dst, make([]byte, loc.blen)...)
+	_, err := d.f.ReadAt(dst[off:], loc.off+headerSize+int64(loc.klen))
+	d.mux.RUnlock()
+	if err != nil {
+		return cbytecache.Entry{Key: key, Body: dst[:off]}, err
+	}
+	return cbytecache.Entry{Key: key, Body: dst, Expire: loc.expire}, nil
+}
+
+// Delete deletes entry from the store.
+func (d *Disk) Delete(key string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if _, ok := d.idx[key]; !ok {
+		return nil
+	}
+	return d.putLF(key, nil, tombstone, 0)
+}
+
+// Len returns count of alive entries.
+func (d *Disk) Len() (n int) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	now := d.now()
+	for _, loc := range d.idx {
+		if loc.expire >= now {
+			n++
+		}
+	}
+	return
+}
+
+// Size returns segment file size.
+func (d *Disk) Size() int64 {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.size
+}
+
+// Compact rewrites the segment file with only alive entries.
+func (d *Disk) Compact() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.compactLF()
+}
+
+// Close closes the segment file.
+func (d *Disk) Close() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.f.Close()
+}
+
+// Append record and update index.
+func (d *Disk) putLF(key string, body []byte, blen, expire uint32) error {
+	d.expireLF()
+	if rs := int64(headerSize + len(key) + len(body)); d.max > 0 && blen != tombstone && d.size+rs > d.max {
+		// Try to release garbage before reject the entry.
+		if d.dead > 0 {
+			if err := d.compactLF(); err != nil {
+				return err
+			}
+		}
+		if d.size+rs > d.max {
+			return cbytecache.ErrNoSpace
+		}
+	}
+	if old, ok := d.idx[key]; ok {
+		d.dead += old.size()
+		delete(d.idx, key)
+	}
+	binary.LittleEndian.PutUint32(d.hdr[0:4], expire)
+	binary.LittleEndian.PutUint16(d.hdr[4:6], uint16(len(key)))
+	binary.LittleEndian.PutUint32(d.hdr[6:10], blen)
+	if _, err := d.w.Write(d.hdr[:]); err != nil {
+		return err
+	}
+	if _, err := d.w.WriteString(key); err != nil {
+		return err
+	}
+	if _, err := d.w.Write(body); err != nil {
+		return err
+	}
+	// Flush every record to make it readable by ReadAt.
+	if err := d.w.Flush(); err != nil {
+		return err
+	}
+	loc := location{off: d.size, klen: uint16(len(key)), blen: uint32(len(body)), expire: expire}
+	d.size += loc.size()
+	if blen == tombstone {
+		d.dead += loc.size()
+	} else {
+		d.idx[key] = loc
+	}
+	if d.size >= d.compactMin && float64(d.dead) >= float64(d.size)*d.compactRatio {
+		return d.compactLF()
+	}
+	return nil
+}
+
+// Move expired records to garbage. Index checks at most once per second.
+func (d *Disk) expireLF() {
+	now := d.now()
+	if now == d.expireAt {
+		return
+	}
+	d.expireAt = now
+	for key, loc := range d.idx {
+		if loc.expire < now {
+			d.dead += loc.size()
+			delete(d.idx, key)
+		}
+	}
+}
+
+// Rewrite segment with alive entries only.
+func (d *Disk) compactLF() error {
+	tmp := d.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	idx := make(map[string]location, len(d.idx))
+	now := d.now()
+	var (
+		size int64
+		buf  []byte
+	)
+	for key, loc := range d.idx {
+		if loc.expire < now {
+			continue
+		}
+		if cap(buf) < int(loc.size()) {
+			buf = make([]byte, loc.size())
+		}
+		buf = buf[:loc.size()]
+		if _, err = d.f.ReadAt(buf, loc.off); err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+			return err
+		}
+		if _, err = w.Write(buf); err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+			return err
+		}
+		loc.off = size
+		idx[key] = loc
+		size += loc.size()
+	}
+	if err = w.Flush(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, d.path); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = d.f.Close()
+	if _, err = f.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	d.f, d.w = f, bufio.NewWriter(f)
+	d.idx, d.size, d.dead = idx, size, 0
+	return nil
+}
+
+// Read segment file and restore the index.
+func (d *Disk) restore() error {
+	r := bufio.NewReader(d.f)
+	var (
+		hdr [headerSize]byte
+		key []byte
+	)
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				// Truncated tail, possibly after crash.
+				return d.f.Truncate(d.size)
+			}
+			return err
+		}
+		loc := location{
+			off:    d.size,
+			expire: binary.LittleEndian.Uint32(hdr[0:4]),
+			klen:   binary.LittleEndian.Uint16(hdr[4:6]),
+			blen:   binary.LittleEndian.Uint32(hdr[6:10]),
+		}
+		del := loc.blen == tombstone
+		if del {
+			loc.blen = 0
+		}
+		key = append(key[:0], make([]byte, loc.klen)...)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return d.f.Truncate(d.size)
+		}
+		if _, err := r.Discard(int(loc.blen)); err != nil {
+			return d.f.Truncate(d.size)
+		}
+		k := byteconv.B2S(key)
+		if old, ok := d.idx[k]; ok {
+			d.dead += old.size()
+			delete(d.idx, k)
+		}
+		d.size += loc.size()
+		if del {
+			d.dead += loc.size()
+		} else {
+			d.idx[string(key)] = loc
+		}
+	}
+}
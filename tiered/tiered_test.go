@@ -0,0 +1,173 @@
+package tiered
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/koykov/cbytecache"
+	"github.com/koykov/hash/fnv"
+)
+
+func body(i int) []byte {
+	return bytes.Repeat([]byte(fmt.Sprintf("%08d", i)), 128)
+}
+
+func TestTiered(t *testing.T) {
+	const n = 1000
+	path := filepath.Join(t.TempDir(), "segment")
+	conf := cbytecache.DefaultConfig(time.Minute, &fnv.Hasher{}, 256*cbytecache.Kilobyte)
+	conf.Buckets = 4
+	c, err := New(conf, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err = c.Set(fmt.Sprintf("key%d", i), body(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spilled := c.Disk().Len()
+	if spilled == 0 {
+		t.Fatal("entries must spill to disk")
+	}
+	for i := 0; i < n; i++ {
+		b, err := c.Get(fmt.Sprintf("key%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, body(i)) {
+			t.Fatalf("key%d body mismatch", i)
+		}
+	}
+
+	t.Run("overwrite", func(t *testing.T) {
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("key%d", i)
+			if _, err := c.Disk().GetTo(nil, key); err == nil {
+				if err = c.Set(key, body(n+i)); err != nil {
+					t.Fatal(err)
+				}
+				if b, err := c.Get(key); err != nil || !bytes.Equal(b, body(n+i)) {
+					t.Errorf("%s must be overwritten: %v", key, err)
+				}
+				// Next subtests check original bodies.
+				if err = c.Delete(key); err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("key%d", i)
+			if _, err := c.Disk().GetTo(nil, key); err == nil {
+				if _, err = c.Extract(key); err != nil {
+					t.Fatal(err)
+				}
+				if _, err = c.Get(key); err != cbytecache.ErrNotFound {
+					t.Errorf("extracted entry must be deleted, got %v", err)
+				}
+				break
+			}
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		size, l := c.Disk().Size(), c.Disk().Len()
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if c, err = New(conf, path); err != nil {
+			t.Fatal(err)
+		}
+		if d := c.Disk(); d.Len() != l || d.Size() != size {
+			t.Errorf("restored segment mismatch: need %d/%d got %d/%d", l, size, d.Len(), d.Size())
+		}
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		var (
+			key string
+			i   int
+		)
+		for i = 0; i < n; i++ {
+			key = fmt.Sprintf("key%d", i)
+			if _, err := c.Disk().GetTo(nil, key); err == nil {
+				break
+			}
+		}
+		b, err := c.Get(key)
+		if err != nil || !bytes.Equal(b, body(i)) {
+			t.Fatalf("disk entry mismatch: %v", err)
+		}
+		if _, err = c.Disk().GetTo(nil, key); err != cbytecache.ErrNotFound {
+			t.Error("promoted entry must be removed from disk")
+		}
+		if b, err = c.Memory().Get(key); err != nil || !bytes.Equal(b, body(i)) {
+			t.Errorf("promoted entry mismatch: %v", err)
+		}
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		d := c.Disk()
+		size, l := d.Size(), d.Len()
+		if err = d.Compact(); err != nil {
+			t.Fatal(err)
+		}
+		if d.Len() != l || d.Size() >= size {
+			t.Errorf("compacted segment mismatch: len %d size %d", d.Len(), d.Size())
+		}
+		for i := 0; i < n; i++ {
+			key := fmt.Sprintf("key%d", i)
+			if e, err := d.GetTo(nil, key); err == nil && !bytes.Equal(e.Body, body(i)) {
+				t.Fatalf("%s body mismatch after compaction", key)
+			}
+		}
+	})
+	_ = c.Close()
+}
+
+func TestDisk(t *testing.T) {
+	now := uint32(1000)
+	d, err := OpenDisk(filepath.Join(t.TempDir(), "segment"), func() uint32 { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = d.Close() }()
+
+	t.Run("expire", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_ = d.Put(cbytecache.Entry{Key: fmt.Sprintf("key%d", i), Body: body(i), Expire: now + uint32(i)})
+		}
+		now += 5
+		if d.Len() != 5 {
+			t.Errorf("len mismatch: need 5 got %d", d.Len())
+		}
+		_ = d.Put(cbytecache.Entry{Key: "foo", Body: body(0), Expire: now + 100})
+		if err = d.Compact(); err != nil {
+			t.Fatal(err)
+		}
+		if l, size := d.Len(), d.Size(); l != 6 || size != 6*int64(headerSize+4+len(body(0)))-1 {
+			t.Errorf("compacted segment mismatch: len %d size %d", l, size)
+		}
+	})
+	t.Run("max size", func(t *testing.T) {
+		d.SetMaxSize(cbytecache.MemorySize(d.Size()) + 2*cbytecache.Kilobyte)
+		if err = d.Put(cbytecache.Entry{Key: "bar", Body: body(1), Expire: now + 100}); err != nil {
+			t.Fatal(err)
+		}
+		if err = d.Put(cbytecache.Entry{Key: "qux", Body: body(2), Expire: now + 100}); err != cbytecache.ErrNoSpace {
+			t.Errorf("error mismatch: need ErrNoSpace got %v", err)
+		}
+		// Expired entries release space.
+		now += 10
+		if err = d.Put(cbytecache.Entry{Key: "qux", Body: body(2), Expire: now + 100}); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -20,6 +20,12 @@ type Entry struct {
 	Key    string
 	Body   []byte
 	Expire uint32
+	// Namespace ID, zero means default namespace (see Cache.Namespace).
+	Namespace uint16
+	// Tags contains hashes of entry tags (see Cache.SetWithTags).
+	Tags []uint64
+	// Version of the entry (CAS token), see Cache.SetIfVersion. Fills only on read and doesn't restore on write.
+	Version uint64
 }
 
 // Copy copies entry to avoid overwrite entry data.
@@ -31,10 +37,15 @@ func (e Entry) Copy() Entry {
 	cpy.Key = byteconv.B2S(buf[:len(e.Key)])
 	cpy.Body = buf[len(e.Key):]
 	cpy.Expire = e.Expire
+	cpy.Namespace = e.Namespace
+	cpy.Version = e.Version
+	if len(e.Tags) > 0 {
+		cpy.Tags = append([]uint64(nil), e.Tags...)
+	}
 	return cpy
 }
 
 // Size returns entry size in bytes.
 func (e Entry) Size() int {
-	return len(e.Key) + len(e.Body) + 4
+	return len(e.Key) + len(e.Body) + 4 + len(e.Tags)*8
 }